@@ -0,0 +1,220 @@
+package api
+
+import (
+	"cnr-tp/domain"
+	"cnr-tp/importer"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImportHandler struct {
+	importUseCase domain.ImportUseCase
+}
+
+func NewImportHandler(importUseCase domain.ImportUseCase) *ImportHandler {
+	return &ImportHandler{importUseCase: importUseCase}
+}
+
+type ImportRequest struct {
+	Format        string   `form:"format" binding:"omitempty,oneof=xlsx csv tsv ndjson"`
+	Delimiter     string   `form:"delimiter" binding:"omitempty,len=1"`
+	Encoding      string   `form:"encoding"`
+	Mode          string   `form:"mode" binding:"omitempty,oneof=best_effort atomic"`
+	BatchSize     int      `form:"batch_size" binding:"omitempty,min=1,max=10000"`
+	MaxRejectRate *float64 `form:"max_reject_rate" binding:"omitempty,min=0,max=1"`
+	Upsert        *bool    `form:"upsert"`
+	UpsertKey     string   `form:"upsert_key" binding:"omitempty,oneof=npens npens_ag"`
+	DateLayouts   []string `form:"date_layouts"`
+	Password      string   `form:"password"`
+	Sheets        []string `form:"sheets"`
+	SheetAsWilaya bool     `form:"sheet_as_wilaya"`
+}
+
+// bindUpload reads the uploaded file and the import options of the request.
+// It writes the error response itself and returns false on failure.
+func bindUpload(c *gin.Context) (*multipart.FileHeader, domain.ImportOptions, bool) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file field is required"})
+		return nil, domain.ImportOptions{}, false
+	}
+
+	var req ImportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, domain.ImportOptions{}, false
+	}
+
+	if _, err := importer.DetectFormat(fileHeader.Filename, domain.ImportFormat(req.Format)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, domain.ImportOptions{}, false
+	}
+
+	var passwords []string
+	if req.Password != "" {
+		passwords = []string{req.Password}
+	}
+
+	return fileHeader, domain.ImportOptions{
+		Format:        domain.ImportFormat(req.Format),
+		Delimiter:     req.Delimiter,
+		Encoding:      req.Encoding,
+		Mode:          domain.ImportMode(req.Mode),
+		BatchSize:     req.BatchSize,
+		MaxRejectRate: req.MaxRejectRate,
+		Upsert:        req.Upsert,
+		UpsertKey:     domain.UpsertKey(req.UpsertKey),
+		DateLayouts:   req.DateLayouts,
+		Passwords:     passwords,
+		Sheets:        req.Sheets,
+		SheetAsWilaya: req.SheetAsWilaya,
+	}, true
+}
+
+// UploadImport accepts a multipart file upload (xlsx, CSV, TSV or NDJSON) and
+// starts a background import job
+func (h *ImportHandler) UploadImport(c *gin.Context) {
+	fileHeader, opts, ok := bindUpload(c)
+	if !ok {
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	if userID, ok := c.Get("userID"); ok {
+		id := userID.(uint)
+		opts.UserID = &id
+	}
+
+	job, err := h.importUseCase.StartImport(fileHeader.Filename, file, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start import"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ValidateImport runs every parse and validation rule over the uploaded file
+// without writing to the database, and returns the validation report
+func (h *ImportHandler) ValidateImport(c *gin.Context) {
+	fileHeader, opts, ok := bindUpload(c)
+	if !ok {
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	report, err := h.importUseCase.ValidateImport(fileHeader.Filename, file, opts)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// canAccessJob reports whether the authenticated user may see an import job:
+// admins see every job, other users only the ones they uploaded
+func canAccessJob(c *gin.Context, job *domain.ImportJob) bool {
+	if c.GetString("role") == "admin" {
+		return true
+	}
+	userID, ok := c.Get("userID")
+	return ok && job.Options.UserID != nil && *job.Options.UserID == userID.(uint)
+}
+
+// GetImport returns the status and counters of an import job
+func (h *ImportHandler) GetImport(c *gin.Context) {
+	job, err := h.importUseCase.GetImportJob(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+		return
+	}
+	if !canAccessJob(c, job) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Import job belongs to another user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// GetRejectReport downloads the workbook listing the rows rejected by an import job
+func (h *ImportHandler) GetRejectReport(c *gin.Context) {
+	job, err := h.importUseCase.GetRejectReport(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if !canAccessJob(c, job) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Import job belongs to another user"})
+		return
+	}
+
+	name := strings.TrimSuffix(job.FileName, filepath.Ext(job.FileName)) + "_rejected.xlsx"
+	c.FileAttachment(job.RejectReport, name)
+}
+
+// GetImportBatches lists the recorded import batches, most recent first
+func (h *ImportHandler) GetImportBatches(c *gin.Context) {
+	batches, err := h.importUseCase.GetImportBatches()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch import batches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": batches})
+}
+
+func (h *ImportHandler) GetImportBatch(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	batch, err := h.importUseCase.GetImportBatch(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import batch not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// RollbackImportBatch deletes every pension record created by an import batch
+func (h *ImportHandler) RollbackImportBatch(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if _, err := h.importUseCase.GetImportBatch(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import batch not found"})
+		return
+	}
+
+	batch, err := h.importUseCase.RollbackImportBatch(uint(id))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
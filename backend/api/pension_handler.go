@@ -2,38 +2,58 @@ package api
 
 import (
 	"cnr-tp/domain"
+	"cnr-tp/exporter"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type PensionHandler struct {
 	pensionUseCase domain.PensionUseCase
+	exporter       *exporter.Exporter
 }
 
-func NewPensionHandler(pensionUseCase domain.PensionUseCase) *PensionHandler {
-	return &PensionHandler{pensionUseCase: pensionUseCase}
+func NewPensionHandler(pensionUseCase domain.PensionUseCase, pensionExporter *exporter.Exporter) *PensionHandler {
+	return &PensionHandler{pensionUseCase: pensionUseCase, exporter: pensionExporter}
 }
 
+// GetPensions lists the pensions matching the query filter. The page and
+// limit query parameters paginate the list, which is returned whole when limit
+// is missing or 0, with a page and limit of 0 in its meta; sort and fields are
+// comma-separated lists of JSON field names.
 func (h *PensionHandler) GetPensions(c *gin.Context) {
-	avantagesParam := c.Query("avantages")
-	var avantages []string
-	if avantagesParam != "" {
-		avantages = strings.Split(avantagesParam, ",")
+	query, err := parsePensionQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	pensions, total, err := h.pensionUseCase.GetAllPensions(avantages)
+	pensions, total, err := h.pensionUseCase.GetAllPensions(query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pension data"})
 		return
 	}
 
+	var data interface{} = pensions
+	if len(query.Fields) > 0 {
+		if data, err = selectFields(pensions, query.Fields); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pension data"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"data": pensions,
+		"data": data,
 		"meta": gin.H{
 			"total": total,
+			"page":  query.Page,
+			"limit": query.Limit,
 		},
 	})
 }
@@ -106,21 +126,16 @@ func (h *PensionHandler) DeletePension(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Pension data deleted successfully"})
 }
 
-// GetRiskLevelStats handles fetching risk level statistics
+// GetRiskLevelStats handles fetching risk level statistics for the pension
+// filter in the JSON body
 func (h *PensionHandler) GetRiskLevelStats(c *gin.Context) {
-	var filters struct {
-		Wilaya     string   `json:"wilaya"`
-		Categories []string `json:"categories"`
-		Avantages  []string `json:"avantages"`
-	}
-
-	// Bind the JSON request body to the filters struct
-	if err := c.ShouldBindJSON(&filters); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body format"})
+	filter, err := bindPensionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	stats, err := h.pensionUseCase.GetRiskLevelStats(filters.Wilaya, filters.Categories, filters.Avantages)
+	stats, err := h.pensionUseCase.GetRiskLevelStats(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch risk level statistics"})
 		return
@@ -128,3 +143,181 @@ func (h *PensionHandler) GetRiskLevelStats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stats)
 }
+
+// GetDemographics breaks the records matching the filter down by sex, age
+// band and avantage category. The filter is read like GetRiskLevelStats.
+func (h *PensionHandler) GetDemographics(c *gin.Context) {
+	filter, err := bindPensionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	demographics, err := h.pensionUseCase.GetDemographics(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch demographics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, demographics)
+}
+
+// GetCrossTab counts the records matching the filter for every pair of
+// values of the rows and columns dimensions. The filter is read like
+// GetRiskLevelStats; the dimensions always come from the query string.
+func (h *PensionHandler) GetCrossTab(c *gin.Context) {
+	rows, err := queryDimension(c, "rows", domain.CrossTabDimensions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	columns, err := queryDimension(c, "columns", domain.CrossTabDimensions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if rows == columns {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rows and columns must be different dimensions"})
+		return
+	}
+
+	filter, err := bindPensionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tab, err := h.pensionUseCase.GetCrossTab(filter, rows, columns)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute cross-tabulation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tab)
+}
+
+// Pivot groups the records matching the filter on the dimensions query
+// parameter and computes the measures of each group. The filter is read
+// like GetRiskLevelStats; dimensions and measures always come from the
+// query string.
+func (h *PensionHandler) Pivot(c *gin.Context) {
+	query, err := parsePivotQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.pensionUseCase.Pivot(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute pivot"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExportPensionsXLSX downloads the records matching the query filter as an
+// Excel workbook, with a risk level summary sheet. Filters matching more
+// records than a sheet holds are refused with a pointer to the CSV export.
+func (h *PensionHandler) ExportPensionsXLSX(c *gin.Context) {
+	filter, err := bindPensionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	f, err := h.exporter.PensionWorkbook(filter)
+	if errors.Is(err, exporter.ErrTooManyRecords) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Pension export failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export pension data"})
+		return
+	}
+	defer f.Close()
+
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="pensions_%s.xlsx"`, time.Now().Format("20060102")))
+	if err := h.exporter.Write(f, c.Writer); err != nil {
+		log.Printf("Failed to write pension export: %v", err)
+	}
+}
+
+// ExportRiskReport downloads the risk report of the records matching the query
+// filter: risk level and wilaya breakdowns with their charts
+func (h *PensionHandler) ExportRiskReport(c *gin.Context) {
+	filter, err := bindPensionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	f, err := h.exporter.RiskReport(filter)
+	if err != nil {
+		log.Printf("Risk report failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build risk report"})
+		return
+	}
+	defer f.Close()
+
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="rapport_risque_%s.xlsx"`, time.Now().Format("20060102")))
+	if err := h.exporter.Write(f, c.Writer); err != nil {
+		log.Printf("Failed to write risk report: %v", err)
+	}
+}
+
+// ExportPensionsCSV streams the records matching the query filter as CSV.
+// The delimiter query parameter defaults to a comma.
+func (h *PensionHandler) ExportPensionsCSV(c *gin.Context) {
+	filter, err := bindPensionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	delimiter := ','
+	if d := c.Query("delimiter"); d != "" {
+		runes := []rune(d)
+		if len(runes) != 1 || runes[0] == '"' || runes[0] == '\r' || runes[0] == '\n' {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delimiter"})
+			return
+		}
+		delimiter = runes[0]
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="pensions_%s.csv"`, time.Now().Format("20060102")))
+	if err := h.exporter.WriteCSV(c.Writer, delimiter, filter); err != nil {
+		// The response is already under way: the client gets a truncated file
+		log.Printf("Pension CSV export failed: %v", err)
+	}
+}
+
+// ExportPensionsNDJSON streams the records matching the query filter as
+// newline-delimited JSON
+func (h *PensionHandler) ExportPensionsNDJSON(c *gin.Context) {
+	filter, err := bindPensionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="pensions_%s.ndjson"`, time.Now().Format("20060102")))
+	if err := h.exporter.WriteNDJSON(c.Writer, filter); err != nil {
+		// The response is already under way: the client gets a truncated file
+		log.Printf("Pension NDJSON export failed: %v", err)
+	}
+}
+
+// queryList splits a comma-separated query parameter
+func queryList(c *gin.Context, key string) []string {
+	value := c.Query(key)
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"cnr-tp/domain"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPageLimit bounds the limit query parameter of paginated lists
+const maxPageLimit = 1000
+
+// parsePensionQuery reads the page, limit, sort and fields query parameters
+// of the pension list. sort is a comma-separated list of field names, each
+// prefixed with - for a descending order.
+func parsePensionQuery(c *gin.Context) (domain.PensionQuery, error) {
+	var query domain.PensionQuery
+
+	var err error
+	if query.Filter, err = queryPensionFilter(c); err != nil {
+		return query, err
+	}
+	if query.Page, err = queryInt(c, "page", 1); err != nil || query.Page < 1 {
+		return query, fmt.Errorf("page must be a positive number")
+	}
+	if query.Limit, err = queryInt(c, "limit", 0); err != nil || query.Limit < 0 || query.Limit > maxPageLimit {
+		return query, fmt.Errorf("limit must be between 1 and %d, or 0 for every record", maxPageLimit)
+	}
+	// Without a limit the list is not paginated, so there is no page either
+	if query.Limit == 0 {
+		query.Page = 0
+	}
+
+	for _, field := range queryList(c, "sort") {
+		sort := domain.SortField{Field: strings.TrimSpace(field)}
+		if strings.HasPrefix(sort.Field, "-") {
+			sort.Field, sort.Desc = sort.Field[1:], true
+		}
+		if _, ok := domain.PensionColumns[sort.Field]; !ok {
+			return query, fmt.Errorf("cannot sort on unknown field %q", sort.Field)
+		}
+		query.Sort = append(query.Sort, sort)
+	}
+
+	for _, field := range queryList(c, "fields") {
+		field = strings.TrimSpace(field)
+		if _, ok := domain.PensionColumns[field]; !ok {
+			return query, fmt.Errorf("unknown field %q", field)
+		}
+		query.Fields = append(query.Fields, field)
+	}
+
+	return query, nil
+}
+
+// bindPensionFilter reads the pension filter from the JSON body of POST
+// requests and from the query string otherwise. Both use the JSON names of
+// PensionFilter; in query strings, lists are comma-separated and the bounds
+// of a range are suffixed to its name: net_mens_min, datenais_before.
+func bindPensionFilter(c *gin.Context) (domain.PensionFilter, error) {
+	if c.Request.Method != http.MethodPost {
+		return queryPensionFilter(c)
+	}
+
+	var filter domain.PensionFilter
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		return filter, fmt.Errorf("invalid filter: %v", err)
+	}
+	return filter, filter.Validate()
+}
+
+func queryPensionFilter(c *gin.Context) (domain.PensionFilter, error) {
+	filter := domain.PensionFilter{
+		Wilaya:     c.Query("wilaya"),
+		Agency:     c.Query("agency"),
+		Categories: queryList(c, "categories"),
+		Avantages:  queryList(c, "avantages"),
+		Sexe:       queryList(c, "sexe"),
+	}
+
+	for _, value := range queryList(c, "risk_levels") {
+		level, err := strconv.ParseInt(strings.TrimSpace(value), 10, 8)
+		if err != nil {
+			return filter, fmt.Errorf("invalid risk level %q", value)
+		}
+		filter.RiskLevels = append(filter.RiskLevels, int8(level))
+	}
+
+	for _, r := range filter.NumericRanges() {
+		var err error
+		if r.Range.Min, err = queryFloat(c, r.Field+"_min"); err != nil {
+			return filter, err
+		}
+		if r.Range.Max, err = queryFloat(c, r.Field+"_max"); err != nil {
+			return filter, err
+		}
+	}
+	for _, r := range filter.DateRanges() {
+		var err error
+		if r.Range.After, err = queryDate(c, r.Field+"_after"); err != nil {
+			return filter, err
+		}
+		if r.Range.Before, err = queryDate(c, r.Field+"_before"); err != nil {
+			return filter, err
+		}
+	}
+
+	return filter, filter.Validate()
+}
+
+// queryFloat reads a number query parameter, nil when it is missing
+func queryFloat(c *gin.Context, key string) (*float64, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number", key)
+	}
+	return &f, nil
+}
+
+// queryDate reads a date query parameter, nil when it is missing
+func queryDate(c *gin.Context, key string) (*domain.Date, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	d, err := domain.ParseDate(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", key, err)
+	}
+	return &d, nil
+}
+
+// queryInt reads an integer query parameter, def when it is missing
+func queryInt(c *gin.Context, key string, def int) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// parsePivotQuery reads the dimensions and measures query parameters of a
+// pivot, both comma-separated, and its filter. Measures default to count.
+func parsePivotQuery(c *gin.Context) (domain.PivotQuery, error) {
+	var query domain.PivotQuery
+
+	seen := make(map[string]bool)
+	for _, name := range queryList(c, "dimensions") {
+		name = strings.TrimSpace(name)
+		dimension, ok := findDimension(name, domain.PivotDimensions)
+		if !ok {
+			return query, fmt.Errorf("unknown dimension %q, expected one of %s", name, dimensionNames(domain.PivotDimensions))
+		}
+		if seen[name] {
+			return query, fmt.Errorf("dimension %q is given twice", name)
+		}
+		seen[name] = true
+		query.Dimensions = append(query.Dimensions, dimension)
+	}
+
+	seen = make(map[string]bool)
+	for _, value := range queryList(c, "measures") {
+		measure, err := domain.ParseMeasure(value)
+		if err != nil {
+			return query, err
+		}
+		if seen[measure.Name()] {
+			return query, fmt.Errorf("measure %q is given twice", value)
+		}
+		seen[measure.Name()] = true
+		query.Measures = append(query.Measures, measure)
+	}
+	if len(query.Measures) == 0 {
+		query.Measures = []domain.Measure{{Func: domain.MeasureCount}}
+	}
+
+	var err error
+	query.Filter, err = bindPensionFilter(c)
+	return query, err
+}
+
+// queryDimension reads a required query parameter naming one of the allowed
+// dimensions
+func queryDimension(c *gin.Context, key string, allowed []domain.Dimension) (domain.Dimension, error) {
+	value := strings.TrimSpace(c.Query(key))
+	if value == "" {
+		return "", fmt.Errorf("%s is required", key)
+	}
+	dimension, ok := findDimension(value, allowed)
+	if !ok {
+		return "", fmt.Errorf("unknown %s dimension %q, expected one of %s", key, value, dimensionNames(allowed))
+	}
+	return dimension, nil
+}
+
+func findDimension(name string, allowed []domain.Dimension) (domain.Dimension, bool) {
+	for _, dimension := range allowed {
+		if string(dimension) == name {
+			return dimension, true
+		}
+	}
+	return "", false
+}
+
+func dimensionNames(dimensions []domain.Dimension) string {
+	names := make([]string, 0, len(dimensions))
+	for _, dimension := range dimensions {
+		names = append(names, string(dimension))
+	}
+	return strings.Join(names, ", ")
+}
+
+// selectFields keeps only the given JSON fields of each pension
+func selectFields(pensions []domain.PensionData, fields []string) ([]map[string]json.RawMessage, error) {
+	rows := make([]map[string]json.RawMessage, 0, len(pensions))
+	for i := range pensions {
+		data, err := json.Marshal(&pensions[i])
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				row[field] = value
+			} else {
+				// omitempty provenance fields
+				row[field] = json.RawMessage("null")
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"cnr-tp/domain"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReferenceHandler struct {
+	referenceUseCase domain.ReferenceUseCase
+}
+
+func NewReferenceHandler(referenceUseCase domain.ReferenceUseCase) *ReferenceHandler {
+	return &ReferenceHandler{referenceUseCase: referenceUseCase}
+}
+
+// GetReferenceData returns every code list: avantage and pension categories,
+// geography and risk levels
+func (h *ReferenceHandler) GetReferenceData(c *gin.Context) {
+	data, err := h.referenceUseCase.GetReferenceData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reference data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// SaveAvantageCategory creates or replaces the avantage category named in the path
+func (h *ReferenceHandler) SaveAvantageCategory(c *gin.Context) {
+	var category domain.AvantageCategory
+	if err := c.ShouldBindJSON(&category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category.Name = c.Param("name")
+	if err := h.referenceUseCase.SaveAvantageCategory(&category); err != nil {
+		referenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+func (h *ReferenceHandler) DeleteAvantageCategory(c *gin.Context) {
+	if err := h.referenceUseCase.DeleteAvantageCategory(c.Param("name")); err != nil {
+		referenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Avantage category deleted successfully"})
+}
+
+// SavePensionCategory creates or replaces the pension category named in the path
+func (h *ReferenceHandler) SavePensionCategory(c *gin.Context) {
+	var category domain.PensionCategory
+	if err := c.ShouldBindJSON(&category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category.Name = c.Param("name")
+	if err := h.referenceUseCase.SavePensionCategory(&category); err != nil {
+		referenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+func (h *ReferenceHandler) DeletePensionCategory(c *gin.Context) {
+	if err := h.referenceUseCase.DeletePensionCategory(c.Param("name")); err != nil {
+		referenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pension category deleted successfully"})
+}
+
+// SaveWilaya creates or renames the wilaya with the code in the path
+func (h *ReferenceHandler) SaveWilaya(c *gin.Context) {
+	code, err := strconv.Atoi(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wilaya code"})
+		return
+	}
+
+	var wilaya domain.Wilaya
+	if err := c.ShouldBindJSON(&wilaya); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wilaya.Code = code
+	if err := h.referenceUseCase.SaveWilaya(&wilaya); err != nil {
+		referenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, wilaya)
+}
+
+func (h *ReferenceHandler) DeleteWilaya(c *gin.Context) {
+	code, err := strconv.Atoi(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wilaya code"})
+		return
+	}
+
+	if err := h.referenceUseCase.DeleteWilaya(code); err != nil {
+		referenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Wilaya deleted successfully"})
+}
+
+// SaveAgency creates or replaces the agency with the AG code in the path
+func (h *ReferenceHandler) SaveAgency(c *gin.Context) {
+	code, err := strconv.ParseInt(c.Param("code"), 10, 8)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agency code"})
+		return
+	}
+
+	var agency domain.Agency
+	if err := c.ShouldBindJSON(&agency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	agency.Code = int8(code)
+	if err := h.referenceUseCase.SaveAgency(&agency); err != nil {
+		referenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, agency)
+}
+
+func (h *ReferenceHandler) DeleteAgency(c *gin.Context) {
+	code, err := strconv.ParseInt(c.Param("code"), 10, 8)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agency code"})
+		return
+	}
+
+	if err := h.referenceUseCase.DeleteAgency(int8(code)); err != nil {
+		referenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Agency deleted successfully"})
+}
+
+// SaveCommune creates or replaces the commune with the code in the path
+func (h *ReferenceHandler) SaveCommune(c *gin.Context) {
+	code, err := strconv.Atoi(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid commune code"})
+		return
+	}
+
+	var commune domain.Commune
+	if err := c.ShouldBindJSON(&commune); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	commune.Code = code
+	if err := h.referenceUseCase.SaveCommune(&commune); err != nil {
+		referenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, commune)
+}
+
+func (h *ReferenceHandler) DeleteCommune(c *gin.Context) {
+	code, err := strconv.Atoi(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid commune code"})
+		return
+	}
+
+	if err := h.referenceUseCase.DeleteCommune(code); err != nil {
+		referenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Commune deleted successfully"})
+}
+
+// SaveRiskLevel relabels the risk level in the path
+func (h *ReferenceHandler) SaveRiskLevel(c *gin.Context) {
+	level, err := strconv.ParseInt(c.Param("level"), 10, 8)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid risk level"})
+		return
+	}
+
+	var riskLevel domain.RiskLevel
+	if err := c.ShouldBindJSON(&riskLevel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	riskLevel.Level = int8(level)
+	if err := h.referenceUseCase.SaveRiskLevel(&riskLevel); err != nil {
+		referenceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, riskLevel)
+}
+
+// referenceError answers a failed reference data change
+func referenceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrReferenceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save reference data"})
+	}
+}
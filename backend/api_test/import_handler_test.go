@@ -0,0 +1,76 @@
+package api_test
+
+import (
+	"cnr-tp/api"
+	"cnr-tp/domain"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubImportUseCase serves one import job uploaded by a given user
+type stubImportUseCase struct {
+	domain.ImportUseCase
+	job domain.ImportJob
+}
+
+func (s *stubImportUseCase) GetImportJob(id string) (*domain.ImportJob, error) {
+	if id != s.job.ID {
+		return nil, errors.New("import job not found")
+	}
+	job := s.job
+	return &job, nil
+}
+
+func (s *stubImportUseCase) GetRejectReport(id string) (*domain.ImportJob, error) {
+	return s.GetImportJob(id)
+}
+
+// importJobRouter serves the import job routes to the given user, the way
+// AuthMiddleware sets them from the token
+func importJobRouter(uc domain.ImportUseCase, userID uint, role string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Set("role", role)
+	})
+	handler := api.NewImportHandler(uc)
+	router.GET("/imports/:id", handler.GetImport)
+	router.GET("/imports/:id/rejected", handler.GetRejectReport)
+	return router
+}
+
+func TestGetImport_Access(t *testing.T) {
+	owner := uint(7)
+	uc := &stubImportUseCase{job: domain.ImportJob{ID: "job1", Options: domain.ImportOptions{UserID: &owner}}}
+
+	for _, tc := range []struct {
+		name   string
+		userID uint
+		role   string
+		status int
+	}{
+		{"uploader", 7, "user", http.StatusOK},
+		{"admin", 1, "admin", http.StatusOK},
+		{"other user", 8, "user", http.StatusForbidden},
+	} {
+		w := send(importJobRouter(uc, tc.userID, tc.role), http.MethodGet, "/imports/job1", "")
+		assert.Equal(t, tc.status, w.Code, tc.name)
+	}
+
+	// Test: the rejected rows report is refused to other users as well
+	w := send(importJobRouter(uc, 8, "user"), http.MethodGet, "/imports/job1/rejected", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Test: jobs started without a user, such as by the watcher, are admin only
+	uc.job.Options.UserID = nil
+	w = send(importJobRouter(uc, 7, "user"), http.MethodGet, "/imports/job1", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = send(importJobRouter(uc, 7, "user"), http.MethodGet, "/imports/missing", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
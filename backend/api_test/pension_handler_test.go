@@ -0,0 +1,336 @@
+package api_test
+
+import (
+	"cnr-tp/api"
+	"cnr-tp/domain"
+	"cnr-tp/exporter"
+	"cnr-tp/usecase"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubPensionUseCase records the query or filter it receives and serves fixed records
+type stubPensionUseCase struct {
+	domain.PensionUseCase
+	query    domain.PensionQuery
+	filter   domain.PensionFilter
+	pivot    domain.PivotQuery
+	pensions []domain.PensionData
+}
+
+func (s *stubPensionUseCase) GetAllPensions(query domain.PensionQuery) ([]domain.PensionData, int64, error) {
+	s.query = query
+	return s.pensions, 42, nil
+}
+
+func (s *stubPensionUseCase) GetRiskLevelStats(filter domain.PensionFilter) ([]domain.RiskLevelStats, error) {
+	s.filter = filter
+	return []domain.RiskLevelStats{}, nil
+}
+
+func (s *stubPensionUseCase) GetDemographics(filter domain.PensionFilter) (*domain.Demographics, error) {
+	s.filter = filter
+	return &domain.Demographics{
+		Total: 4,
+		Sexe:  []domain.DemographicGroup{{Key: "F", Label: "Femmes", Count: 3, Percentage: 75}, {Key: "M", Label: "Hommes", Count: 1, Percentage: 25}},
+	}, nil
+}
+
+func (s *stubPensionUseCase) Pivot(query domain.PivotQuery) (*domain.PivotResult, error) {
+	s.filter = query.Filter
+	s.pivot = query
+	return &domain.PivotResult{
+		Columns: []domain.PivotColumn{{Name: "ag", Kind: "dimension"}, {Name: "ag_label", Kind: "label"}, {Name: "count", Kind: "measure"}},
+		Rows:    [][]interface{}{{"16", "Alger", 12}},
+	}, nil
+}
+
+func getPensions(uc domain.PensionUseCase, url string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/pensions", api.NewPensionHandler(uc, nil).GetPensions)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+	return w
+}
+
+func TestGetPensions_Pagination(t *testing.T) {
+	uc := &stubPensionUseCase{pensions: []domain.PensionData{{ID: 7, NPens: "P7", NetMens: 1500}}}
+
+	w := getPensions(uc, "/pensions?page=3&limit=20&sort=-net_mens,npens&avantages=3,F")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, domain.PensionQuery{
+		Filter: domain.PensionFilter{Avantages: []string{"3", "F"}},
+		Page:   3,
+		Limit:  20,
+		Sort:   []domain.SortField{{Field: "net_mens", Desc: true}, {Field: "npens"}},
+	}, uc.query)
+
+	var body struct {
+		Data []domain.PensionData `json:"data"`
+		Meta struct {
+			Total int64 `json:"total"`
+			Page  int   `json:"page"`
+			Limit int   `json:"limit"`
+		} `json:"meta"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.Data, 1)
+	assert.Equal(t, int64(42), body.Meta.Total)
+	assert.Equal(t, 3, body.Meta.Page)
+	assert.Equal(t, 20, body.Meta.Limit)
+}
+
+func TestGetPensions_Unpaginated(t *testing.T) {
+	for _, url := range []string{"/pensions?page=4", "/pensions?page=4&limit=0"} {
+		uc := &stubPensionUseCase{pensions: []domain.PensionData{{ID: 7, NPens: "P7"}}}
+
+		w := getPensions(uc, url)
+		assert.Equal(t, http.StatusOK, w.Code, url)
+		assert.Zero(t, uc.query.Page, url)
+		assert.Zero(t, uc.query.Limit, url)
+
+		var body struct {
+			Meta map[string]int64 `json:"meta"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, map[string]int64{"total": 42, "page": 0, "limit": 0}, body.Meta, url)
+	}
+}
+
+func TestGetPensions_Fields(t *testing.T) {
+	uc := &stubPensionUseCase{pensions: []domain.PensionData{{ID: 7, NPens: "P7", NetMens: 1500}}}
+
+	w := getPensions(uc, "/pensions?fields=id,npens,source_row")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"id", "npens", "source_row"}, uc.query.Fields)
+
+	var body struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, []map[string]interface{}{{"id": 7.0, "npens": "P7", "source_row": nil}}, body.Data)
+}
+
+func TestGetPensions_InvalidQuery(t *testing.T) {
+	for _, url := range []string{
+		"/pensions?page=0",
+		"/pensions?limit=abc",
+		"/pensions?limit=5000",
+		"/pensions?sort=password",
+		"/pensions?fields=id,unknown",
+		"/pensions?risk_levels=5",
+		"/pensions?net_mens_min=high",
+		"/pensions?datenais_before=01/01/1935",
+		"/pensions?net_mens_min=5000&net_mens_max=1000",
+		"/pensions?duree_pension_min=NaN",
+		"/pensions?datenais_after=1940-01-01&datenais_before=1935-01-01",
+		"/pensions?datjouis_after=0001-01-01",
+	} {
+		w := getPensions(&stubPensionUseCase{}, url)
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
+
+// oversizedPensionUseCase matches more records than an Excel sheet holds
+type oversizedPensionUseCase struct {
+	domain.PensionUseCase
+}
+
+func (oversizedPensionUseCase) GetRiskLevelStats(filter domain.PensionFilter) ([]domain.RiskLevelStats, error) {
+	return []domain.RiskLevelStats{{RiskLevel: "Bas risque", Count: exporter.MaxWorkbookRecords + 1}}, nil
+}
+
+func TestExportPensionsXLSX_TooManyRecords(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	uc := oversizedPensionUseCase{}
+	router := gin.New()
+	router.GET("/export.xlsx", api.NewPensionHandler(uc, exporter.New(uc, nil, "")).ExportPensionsXLSX)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export.xlsx", nil))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "export.csv")
+}
+
+func TestPensionFilter_QueryAndJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	uc := &stubPensionUseCase{}
+	router := gin.New()
+	router.GET("/pensions", api.NewPensionHandler(uc, nil).GetPensions)
+	router.POST("/pensions/risk-stats", api.NewPensionHandler(uc, nil).GetRiskLevelStats)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"/pensions?wilaya=16&agency=Alger&categories=A,S&avantages=Veuves&sexe=F&risk_levels=1,2&net_mens_min=1000&datenais_before=1935-01-01", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pensions/risk-stats", strings.NewReader(`{
+		"wilaya": "16", "agency": "Alger", "categories": ["A", "S"], "avantages": ["Veuves"], "sexe": ["F"], "risk_levels": [1, 2],
+		"net_mens": {"min": 1000}, "datenais": {"before": "1935-01-01"}
+	}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Test: both requests describe the same filter
+	assert.Equal(t, uc.query.Filter, uc.filter)
+	assert.Equal(t, "Alger", uc.filter.Agency)
+	assert.Equal(t, 1000.0, *uc.filter.NetMens.Min)
+	assert.Equal(t, 1935, uc.filter.DateNais.Before.Year())
+}
+
+func TestPensionFilter_Ranges(t *testing.T) {
+	uc := &stubPensionUseCase{}
+
+	w := getPensions(uc, "/pensions?duree_pension_min=30&taux_glb_max=50.5&age_app_tp_min=60&age_app_tp_max=60&ag_min=10&ag_max=20")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	filter := uc.query.Filter
+	assert.Equal(t, 30.0, *filter.DureePension.Min)
+	assert.Nil(t, filter.DureePension.Max)
+	assert.Equal(t, 50.5, *filter.TauxGLB.Max)
+	assert.Equal(t, 60.0, *filter.AgeAppTP.Min, "Equal bounds select a single value")
+	assert.Equal(t, 60.0, *filter.AgeAppTP.Max)
+	assert.Equal(t, 10.0, *filter.AG.Min)
+	assert.Equal(t, 20.0, *filter.AG.Max)
+}
+
+func TestGetDemographics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	uc := &stubPensionUseCase{}
+	router := gin.New()
+	router.GET("/pensions/demographics", api.NewPensionHandler(uc, nil).GetDemographics)
+	router.POST("/pensions/demographics", api.NewPensionHandler(uc, nil).GetDemographics)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pensions/demographics?wilaya=Oran&risk_levels=2", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, domain.PensionFilter{Wilaya: "Oran", RiskLevels: []int8{2}}, uc.filter)
+
+	var body domain.Demographics
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 4, body.Total)
+	assert.Equal(t, "Femmes", body.Sexe[0].Label)
+
+	// Test: the JSON filter of the risk statistics is accepted as well
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pensions/demographics", strings.NewReader(`{"sexe": ["F"]}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"F"}, uc.filter.Sexe)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pensions/demographics?risk_levels=5", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// stubPensionRepository serves fixed cross-tabulation counts
+type stubPensionRepository struct {
+	domain.PensionRepository
+	counts []domain.CrossTabCount
+}
+
+func (r *stubPensionRepository) GetCrossTab(filter domain.PensionFilter, rows, columns domain.Dimension) ([]domain.CrossTabCount, error) {
+	return r.counts, nil
+}
+
+func TestGetCrossTab(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &stubPensionRepository{counts: []domain.CrossTabCount{
+		{RowKey: "16", RowLabel: "Alger", ColumnKey: "0", ColumnLabel: "Bas risque", Count: 6},
+		{RowKey: "16", RowLabel: "Alger", ColumnKey: "2", ColumnLabel: "Haut risque", Count: 2},
+		{RowKey: "31", RowLabel: "Oran", ColumnKey: "1", ColumnLabel: "Moyen risque", Count: 1},
+		{RowKey: "31", RowLabel: "Oran", ColumnKey: "2", ColumnLabel: "Haut risque", Count: 1},
+	}}
+	router := gin.New()
+	router.GET("/pensions/crosstab", api.NewPensionHandler(usecase.NewPensionUseCase(repo), nil).GetCrossTab)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pensions/crosstab?rows=ag&columns=risk_level&sexe=F", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var tab domain.CrossTab
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tab))
+	assert.Equal(t, domain.DimensionAgency, tab.RowDimension)
+	assert.Equal(t, 10, tab.Total)
+
+	// Test: columns are ordered by key and missing pairs are zero cells
+	assert.Equal(t, []domain.CrossTabColumn{
+		{Key: "0", Label: "Bas risque", Total: 6, Percentage: 60},
+		{Key: "1", Label: "Moyen risque", Total: 1, Percentage: 10},
+		{Key: "2", Label: "Haut risque", Total: 3, Percentage: 30},
+	}, tab.Columns)
+	assert.Equal(t, domain.CrossTabRow{
+		Key: "16", Label: "Alger", Total: 8, Percentage: 80,
+		Cells: []domain.CrossTabCell{{Count: 6, Percentage: 75}, {Count: 0, Percentage: 0}, {Count: 2, Percentage: 25}},
+	}, tab.Rows[0])
+	assert.Equal(t, []domain.CrossTabCell{{Count: 0, Percentage: 0}, {Count: 1, Percentage: 50}, {Count: 1, Percentage: 50}}, tab.Rows[1].Cells)
+
+	for _, url := range []string{
+		"/pensions/crosstab?columns=risk_level",
+		"/pensions/crosstab?rows=ag&columns=net_mens",
+		"/pensions/crosstab?rows=ag&columns=ag",
+		"/pensions/crosstab?rows=ag&columns=sexe&risk_levels=7",
+	} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
+
+func TestPivot(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	uc := &stubPensionUseCase{}
+	router := gin.New()
+	router.GET("/pensions/pivot", api.NewPensionHandler(uc, nil).Pivot)
+	router.POST("/pensions/pivot", api.NewPensionHandler(uc, nil).Pivot)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"/pensions/pivot?dimensions=ag,birth_year_band&measures=count,avg:net_mens,p90:duree_pension&sexe=F", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, domain.PivotQuery{
+		Filter:     domain.PensionFilter{Sexe: []string{"F"}},
+		Dimensions: []domain.Dimension{domain.DimensionAgency, domain.DimensionBirthYearBand},
+		Measures: []domain.Measure{
+			{Func: domain.MeasureCount},
+			{Func: domain.MeasureAvg, Field: "net_mens"},
+			{Func: domain.MeasurePercentile, Field: "duree_pension", Percentile: 90},
+		},
+	}, uc.pivot)
+	assert.Equal(t, "p90_duree_pension", uc.pivot.Measures[2].Name())
+	assert.JSONEq(t, `{
+		"columns": [{"name": "ag", "kind": "dimension"}, {"name": "ag_label", "kind": "label"}, {"name": "count", "kind": "measure"}],
+		"rows": [["16", "Alger", 12]]
+	}`, w.Body.String())
+
+	// Test: measures default to a count, and POST reads the filter from the body
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pensions/pivot?dimensions=jouissance_year", strings.NewReader(`{"risk_levels": [2]}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []domain.Measure{{Func: domain.MeasureCount}}, uc.pivot.Measures)
+	assert.Equal(t, []int8{2}, uc.pivot.Filter.RiskLevels)
+
+	for _, url := range []string{
+		"/pensions/pivot?dimensions=npens",
+		"/pensions/pivot?dimensions=ag,ag",
+		"/pensions/pivot?measures=sum",
+		"/pensions/pivot?measures=sum:taux_d",
+		"/pensions/pivot?measures=median:net_mens",
+		"/pensions/pivot?measures=p0:net_mens",
+		"/pensions/pivot?measures=p100:net_mens",
+		"/pensions/pivot?measures=max:net_mens,max:net_mens",
+		"/pensions/pivot?measures=count&net_mens_min=abc",
+	} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
@@ -0,0 +1,108 @@
+package api_test
+
+import (
+	"cnr-tp/api"
+	"cnr-tp/domain"
+	"cnr-tp/usecase"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryReferenceRepository keeps the reference data in memory
+type memoryReferenceRepository struct {
+	domain.ReferenceRepository
+	data  *domain.ReferenceData
+	saved []interface{}
+}
+
+func (r *memoryReferenceRepository) Get() (*domain.ReferenceData, error) {
+	return r.data, nil
+}
+
+func (r *memoryReferenceRepository) SaveAvantageCategory(category *domain.AvantageCategory) error {
+	r.saved = append(r.saved, *category)
+	return nil
+}
+
+func (r *memoryReferenceRepository) SaveRiskLevel(level *domain.RiskLevel) error {
+	r.saved = append(r.saved, *level)
+	return nil
+}
+
+func (r *memoryReferenceRepository) SaveAgency(agency *domain.Agency) error {
+	r.saved = append(r.saved, *agency)
+	return nil
+}
+
+func (r *memoryReferenceRepository) DeleteWilaya(code int) error {
+	for _, w := range r.data.Wilayas {
+		if w.Code == code {
+			return nil
+		}
+	}
+	return domain.ErrReferenceNotFound
+}
+
+func referenceRouter(repo domain.ReferenceRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := api.NewReferenceHandler(usecase.NewReferenceUseCase(repo))
+	router.GET("/reference", handler.GetReferenceData)
+	router.PUT("/reference/avantage-categories/:name", handler.SaveAvantageCategory)
+	router.PUT("/reference/risk-levels/:level", handler.SaveRiskLevel)
+	router.DELETE("/reference/wilayas/:code", handler.DeleteWilaya)
+	router.PUT("/reference/agencies/:code", handler.SaveAgency)
+	return router
+}
+
+func send(router *gin.Engine, method, url, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+	return w
+}
+
+func TestReferenceHandler(t *testing.T) {
+	repo := &memoryReferenceRepository{data: domain.DefaultReferenceData()}
+	router := referenceRouter(repo)
+
+	w := send(router, http.MethodGet, "/reference", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"Tizi Ouzou"`)
+
+	// Test: the category is named by the path
+	w = send(router, http.MethodPut, "/reference/avantage-categories/orphelins", `{"label": "Orphelins", "codes": [" K "]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []interface{}{domain.AvantageCategory{Name: "orphelins", Label: "Orphelins", Codes: []string{"K"}}}, repo.saved)
+
+	// Test: a code cannot belong to two categories
+	w = send(router, http.MethodPut, "/reference/avantage-categories/orphelins", `{"codes": ["K", "3"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Veuves")
+
+	w = send(router, http.MethodPut, "/reference/risk-levels/2", `{"label": "Risque élevé"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send(router, http.MethodPut, "/reference/risk-levels/3", `{"label": "Critique"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Test: a wilaya keeps its agencies
+	w = send(router, http.MethodDelete, "/reference/wilayas/16", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "agency 16")
+
+	repo.data.Wilayas = append(repo.data.Wilayas, domain.Wilaya{Code: 59, Name: "Nouvelle wilaya"})
+	assert.Equal(t, http.StatusOK, send(router, http.MethodDelete, "/reference/wilayas/59", "").Code)
+	assert.Equal(t, http.StatusNotFound, send(router, http.MethodDelete, "/reference/wilayas/99", "").Code)
+	assert.Equal(t, http.StatusBadRequest, send(router, http.MethodDelete, "/reference/wilayas/alger", "").Code)
+
+	// Test: agencies belong to a known wilaya and AG codes fit the pension column
+	w = send(router, http.MethodPut, "/reference/agencies/96", `{"name": " Alger Ouest ", "wilaya_code": 16}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, repo.saved, domain.Agency{Code: 96, Name: "Alger Ouest", WilayaCode: 16})
+	assert.Equal(t, http.StatusBadRequest, send(router, http.MethodPut, "/reference/agencies/97", `{"name": "X", "wilaya_code": 99}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send(router, http.MethodPut, "/reference/agencies/200", `{"name": "X", "wilaya_code": 16}`).Code)
+}
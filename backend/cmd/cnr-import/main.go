@@ -0,0 +1,269 @@
+// Command cnr-import loads pension files into the database without starting
+// the HTTP server, and prints a JSON summary of the run on stdout.
+//
+//	cnr-import [flags] <file or directory>...
+//
+// Directories are scanned for supported files, without recursing. The exit
+// status is 0 when every file succeeded, 1 when any failed or, in dry-run
+// mode, had invalid rows, and 2 on a usage error.
+package main
+
+import (
+	"cnr-tp/config"
+	"cnr-tp/domain"
+	"cnr-tp/importer"
+	"cnr-tp/repository"
+	"cnr-tp/usecase"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fileResult is the outcome of one file
+type fileResult struct {
+	File         string                   `json:"file"`
+	Status       domain.ImportStatus      `json:"status"`
+	Error        string                   `json:"error,omitempty"`
+	Summary      *domain.ImportSummary    `json:"summary,omitempty"`
+	Validation   *domain.ValidationReport `json:"validation,omitempty"`
+	RejectReport string                   `json:"reject_report,omitempty"`
+}
+
+type runSummary struct {
+	DryRun    bool         `json:"dry_run"`
+	Files     []fileResult `json:"files"`
+	Completed int          `json:"completed"`
+	Failed    int          `json:"failed"`
+}
+
+// loadConfig reads the configuration from the environment and .env file
+var loadConfig = config.LoadConfig
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout))
+}
+
+// run imports or validates the files named by args, writes the JSON summary
+// to stdout and returns the exit status
+func run(args []string, stdout io.Writer) int {
+	flags := flag.NewFlagSet("cnr-import", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: cnr-import [flags] <file or directory>...")
+		flags.PrintDefaults()
+	}
+
+	dryRun := flags.Bool("dry-run", false, "validate the files without writing to the database")
+	upsert := flags.Bool("upsert", false, "match rows on their natural key instead of inserting them (default from IMPORT_UPSERT)")
+	upsertKey := flags.String("upsert-key", "", "natural key used by -upsert: npens or npens_ag")
+	batchSize := flags.Int("batch-size", 0, "rows per INSERT statement (default from IMPORT_BATCH_SIZE)")
+	mode := flags.String("mode", "", "best_effort or atomic (default from IMPORT_MODE)")
+	sheets := flags.String("sheets", "", "comma-separated list of the workbook sheets to import (default all)")
+	sheetAsWilaya := flags.Bool("sheet-as-wilaya", false, "derive AG and Wilaya from sheet names such as \"16 - Alger\"")
+	password := flags.String("password", "", "password of encrypted workbooks, tried before IMPORT_XLSX_PASSWORDS")
+	reportDir := flags.String("report-dir", "", "directory where rejected rows workbooks are written (default none)")
+	mappingFile := flags.String("mapping", "", "column mapping file (default from IMPORT_MAPPING_FILE)")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	switch domain.ImportMode(*mode) {
+	case "", domain.ImportModeBestEffort, domain.ImportModeAtomic:
+	default:
+		fmt.Fprintf(flags.Output(), "invalid -mode %q: must be best_effort or atomic\n", *mode)
+		return 2
+	}
+	switch domain.UpsertKey(*upsertKey) {
+	case "", domain.UpsertKeyNPens, domain.UpsertKeyNPensAG:
+	default:
+		fmt.Fprintf(flags.Output(), "invalid -upsert-key %q: must be npens or npens_ag\n", *upsertKey)
+		return 2
+	}
+	if *batchSize < 0 {
+		fmt.Fprintln(flags.Output(), "invalid -batch-size: must not be negative")
+		return 2
+	}
+
+	opts := domain.ImportOptions{
+		Mode:          domain.ImportMode(*mode),
+		BatchSize:     *batchSize,
+		UpsertKey:     domain.UpsertKey(*upsertKey),
+		SheetAsWilaya: *sheetAsWilaya,
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "upsert" {
+			opts.Upsert = upsert
+		}
+	})
+	for _, sheet := range strings.Split(*sheets, ",") {
+		if sheet = strings.TrimSpace(sheet); sheet != "" {
+			opts.Sheets = append(opts.Sheets, sheet)
+		}
+	}
+	if *password != "" {
+		opts.Passwords = []string{*password}
+	}
+
+	files, err := collectFiles(flags.Args())
+	if err != nil {
+		log.Print(err)
+		return 2
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("Failed to load configuration: %v", err)
+		return 2
+	}
+	if *mappingFile == "" {
+		*mappingFile = cfg.ImportMappingFile
+	}
+	columnMapping, err := importer.LoadMapping(*mappingFile)
+	if err != nil {
+		log.Printf("Failed to load import column mapping: %v", err)
+		return 2
+	}
+
+	summary := runSummary{DryRun: *dryRun, Files: []fileResult{}}
+	if *dryRun {
+		pensionImporter := importer.New(nil, nil, nil, columnMapping, cfg.ImportDefaults())
+		for _, file := range files {
+			summary.add(validateFile(pensionImporter, file, opts))
+		}
+	} else {
+		pensionImporter, err := openImporter(cfg, columnMapping)
+		if err != nil {
+			log.Print(err)
+			return 2
+		}
+		for _, file := range files {
+			summary.add(importFile(pensionImporter, file, opts, *reportDir))
+		}
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(summary); err != nil {
+		log.Printf("Failed to write summary: %v", err)
+		return 1
+	}
+
+	if summary.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (s *runSummary) add(result fileResult) {
+	s.Files = append(s.Files, result)
+	if result.Status == domain.ImportStatusCompleted {
+		s.Completed++
+	} else {
+		s.Failed++
+	}
+}
+
+// openImporter connects to the database and builds an importer writing to it
+func openImporter(cfg *config.Config, columnMapping *importer.ColumnMapping) (*importer.Importer, error) {
+	// SQL logs go to stderr so that stdout only holds the JSON summary
+	db, err := gorm.Open(mysql.Open(cfg.GetDSN()), &gorm.Config{
+		Logger: logger.New(log.New(os.Stderr, "\r\n", log.LstdFlags), logger.Config{
+			SlowThreshold: 200 * time.Millisecond,
+			LogLevel:      logger.Warn,
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+	if err := repository.AutoMigrate(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %v", err)
+	}
+
+	pensionUseCase := usecase.NewPensionUseCase(repository.NewPensionRepository(db))
+	referenceUseCase := usecase.NewReferenceUseCase(repository.NewReferenceRepository(db))
+	return importer.New(pensionUseCase, referenceUseCase, repository.NewImportBatchRepository(db), columnMapping, cfg.ImportDefaults()), nil
+}
+
+func importFile(pensionImporter *importer.Importer, file string, opts domain.ImportOptions, reportDir string) fileResult {
+	result := fileResult{File: file}
+	if reportDir != "" {
+		opts.RejectReportPath = filepath.Join(reportDir, strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))+importer.RejectReportSuffix)
+	}
+
+	summary, err := pensionImporter.ImportFile(file, opts, nil)
+	result.Summary = &summary
+	if opts.RejectReportPath != "" {
+		if _, statErr := os.Stat(opts.RejectReportPath); statErr == nil {
+			result.RejectReport = opts.RejectReportPath
+		}
+	}
+	if err != nil {
+		result.Status = domain.ImportStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+	result.Status = domain.ImportStatusCompleted
+	return result
+}
+
+func validateFile(pensionImporter *importer.Importer, file string, opts domain.ImportOptions) fileResult {
+	result := fileResult{File: file}
+
+	report, err := pensionImporter.Validate(file, opts)
+	if err != nil {
+		result.Status = domain.ImportStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+	result.Validation = report
+	if report.InvalidRows > 0 {
+		result.Status = domain.ImportStatusFailed
+		result.Error = fmt.Sprintf("%d invalid rows", report.InvalidRows)
+		return result
+	}
+	result.Status = domain.ImportStatusCompleted
+	return result
+}
+
+// collectFiles expands directory arguments into the supported files they hold
+func collectFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$") {
+				continue
+			}
+			if _, err := importer.DetectFormat(name, ""); err == nil {
+				files = append(files, filepath.Join(arg, name))
+			}
+		}
+	}
+	return files, nil
+}
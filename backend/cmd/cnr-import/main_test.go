@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"cnr-tp/config"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pensionHeader = "AG;AVT;NPens;EtatPens;DateNais;DateJouis;SexeTP;NetMens;TauxD;TauxRV;TauxGLB;AgeAppTP;DureePension;AgeMoyenCat;RisqueAge;NiveauRisquePredit\n"
+
+// useTestConfig replaces the .env configuration for the duration of a test
+func useTestConfig(t *testing.T) {
+	t.Helper()
+
+	loadConfig = func() (*config.Config, error) {
+		return &config.Config{ImportDelimiter: ";", ImportMappingFile: "../../config/column_mapping.json"}, nil
+	}
+	t.Cleanup(func() { loadConfig = config.LoadConfig })
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestRun_UsageErrors(t *testing.T) {
+	useTestConfig(t)
+	file := filepath.Join(t.TempDir(), "pensions.csv")
+	writeFile(t, file, pensionHeader)
+
+	for _, args := range [][]string{
+		{},
+		{"-mode", "fast", file},
+		{"-upsert-key", "ag", file},
+		{"-batch-size", "-1", file},
+		{"-unknown", file},
+		{filepath.Join(t.TempDir(), "missing.csv")},
+	} {
+		var stdout bytes.Buffer
+		assert.Equal(t, 2, run(args, &stdout), "%v", args)
+		assert.Empty(t, stdout.String(), "%v: no summary on a usage error", args)
+	}
+}
+
+func TestRun_DryRun(t *testing.T) {
+	useTestConfig(t)
+	dir := t.TempDir()
+	valid := pensionHeader + "16;1;P1;A;1950-03-01;2010-01-01;M;15000;80;0;80;60;14;3;1;2\n"
+	writeFile(t, filepath.Join(dir, "a.csv"), valid)
+	writeFile(t, filepath.Join(dir, "b.csv"), pensionHeader+"16;1;P2;A;1950-03-01;2010-01-01;M;abc;80;0;80;60;14;3;1;2\n")
+	// Neither unsupported, hidden or lock files nor subdirectories are imported
+	writeFile(t, filepath.Join(dir, "notes.pdf"), "notes")
+	writeFile(t, filepath.Join(dir, ".hidden.csv"), valid)
+	writeFile(t, filepath.Join(dir, "~$a.xlsx"), "lock")
+	writeFile(t, filepath.Join(dir, "sub", "c.csv"), valid)
+	single := filepath.Join(t.TempDir(), "single.csv")
+	writeFile(t, single, valid)
+
+	var stdout bytes.Buffer
+	code := run([]string{"-dry-run", "-mode", "atomic", single, dir}, &stdout)
+	assert.Equal(t, 1, code, "A file with invalid rows fails the run")
+
+	var summary struct {
+		DryRun bool `json:"dry_run"`
+		Files  []struct {
+			File       string          `json:"file"`
+			Status     string          `json:"status"`
+			Error      string          `json:"error"`
+			Validation json.RawMessage `json:"validation"`
+			Summary    json.RawMessage `json:"summary"`
+		} `json:"files"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &summary), stdout.String())
+
+	assert.True(t, summary.DryRun)
+	assert.Equal(t, 2, summary.Completed)
+	assert.Equal(t, 1, summary.Failed)
+	require.Len(t, summary.Files, 3)
+
+	assert.Equal(t, single, summary.Files[0].File)
+	assert.Equal(t, filepath.Join(dir, "a.csv"), summary.Files[1].File)
+	assert.Equal(t, filepath.Join(dir, "b.csv"), summary.Files[2].File)
+	assert.Equal(t, "completed", summary.Files[1].Status)
+	assert.Equal(t, "failed", summary.Files[2].Status)
+	assert.Equal(t, "1 invalid rows", summary.Files[2].Error)
+	for _, f := range summary.Files {
+		assert.NotEmpty(t, f.Validation, "Dry runs report the validation of %s", f.File)
+		assert.Empty(t, f.Summary, "Dry runs import nothing")
+	}
+
+	// Test: the run succeeds when every file is valid
+	stdout.Reset()
+	assert.Equal(t, 0, run([]string{"-dry-run", single}, &stdout))
+	assert.Contains(t, stdout.String(), `"completed": 1`)
+}
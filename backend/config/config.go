@@ -1,8 +1,12 @@
 package config
 
 import (
+	"cnr-tp/domain"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +18,23 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	JWTSecret  string
+
+	ImportMappingFile   string
+	ImportDelimiter     string
+	ImportEncoding      string
+	ImportMode          string
+	ImportBatchSize     int
+	ImportMaxRejectRate float64
+	ImportUpsert        bool
+	ImportUpsertKey     string
+	ImportReportDir     string
+	ImportReportTTL     time.Duration
+	ImportProgressEvery int
+	ImportDateLayouts   []string
+	ImportPasswords     []string
+	ExportPassword      string
+	ImportWatchDir      string
+	ImportWatchInterval time.Duration
 }
 
 func LoadConfig() (*Config, error) {
@@ -29,6 +50,23 @@ func LoadConfig() (*Config, error) {
 		DBPassword: getEnv("DB_PASSWORD", ""),
 		DBName:     getEnv("DB_NAME", "cnr_tp"),
 		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
+
+		ImportMappingFile:   getEnv("IMPORT_MAPPING_FILE", "./config/column_mapping.json"),
+		ImportDelimiter:     getEnv("IMPORT_CSV_DELIMITER", ";"),
+		ImportEncoding:      getEnv("IMPORT_ENCODING", "utf-8"),
+		ImportMode:          getEnv("IMPORT_MODE", "best_effort"),
+		ImportBatchSize:     getEnvInt("IMPORT_BATCH_SIZE", 1000),
+		ImportMaxRejectRate: getEnvFloat("IMPORT_MAX_REJECT_RATE", 0.05),
+		ImportUpsert:        getEnvBool("IMPORT_UPSERT", true),
+		ImportUpsertKey:     getEnv("IMPORT_UPSERT_KEY", "npens"),
+		ImportReportDir:     getEnv("IMPORT_REPORT_DIR", "./import_reports"),
+		ImportReportTTL:     getEnvDuration("IMPORT_REPORT_TTL", 7*24*time.Hour),
+		ImportProgressEvery: getEnvInt("IMPORT_PROGRESS_EVERY", 10000),
+		ImportDateLayouts:   getEnvList("IMPORT_DATE_LAYOUTS"),
+		ImportPasswords:     getEnvList("IMPORT_XLSX_PASSWORDS"),
+		ExportPassword:      getEnv("EXPORT_XLSX_PASSWORD", ""),
+		ImportWatchDir:      getEnv("IMPORT_WATCH_DIR", "./excel_data"),
+		ImportWatchInterval: getEnvDuration("IMPORT_WATCH_INTERVAL", 10*time.Second),
 	}
 
 	// config := &Config{
@@ -43,8 +81,26 @@ func LoadConfig() (*Config, error) {
 	return config, nil
 }
 
+// ImportDefaults returns the import options applied when a request or the
+// command line leaves them unset
+func (c *Config) ImportDefaults() domain.ImportOptions {
+	return domain.ImportOptions{
+		Delimiter:     c.ImportDelimiter,
+		Encoding:      c.ImportEncoding,
+		Mode:          domain.ImportMode(c.ImportMode),
+		BatchSize:     c.ImportBatchSize,
+		MaxRejectRate: &c.ImportMaxRejectRate,
+		Upsert:        &c.ImportUpsert,
+		UpsertKey:     domain.UpsertKey(c.ImportUpsertKey),
+		ProgressEvery: c.ImportProgressEvery,
+		DateLayouts:   c.ImportDateLayouts,
+
+		Passwords:            c.ImportPasswords,
+		RejectReportPassword: c.ExportPassword,
+	}
+}
+
 func (c *Config) GetDSN() string {
-	fmt.Println(c.JWTSecret)
 	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		c.DBUser,
 		c.DBPassword,
@@ -61,3 +117,46 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvList splits a comma-separated variable, dropping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,66 @@
+package domain
+
+// Dimension names a categorical field pensions can be grouped on
+type Dimension string
+
+const (
+	// DimensionAgency groups on the agency code (AG)
+	DimensionAgency Dimension = "ag"
+	// DimensionEtatPens groups on the pension state
+	DimensionEtatPens Dimension = "etatpens"
+	// DimensionAvantage groups on the avantage category of the AVT code
+	DimensionAvantage Dimension = "avantage"
+	// DimensionSexe groups on SexeTP
+	DimensionSexe Dimension = "sexe"
+	// DimensionRiskLevel groups on the predicted risk level
+	DimensionRiskLevel Dimension = "risk_level"
+)
+
+// CrossTabDimensions are the dimensions a cross-tabulation accepts
+var CrossTabDimensions = []Dimension{DimensionAgency, DimensionEtatPens, DimensionAvantage, DimensionSexe, DimensionRiskLevel}
+
+// CrossTabCount is the number of pensions sharing one row value and one
+// column value of a cross-tabulation
+type CrossTabCount struct {
+	RowKey      string
+	RowLabel    string
+	ColumnKey   string
+	ColumnLabel string
+	Count       int
+}
+
+// CrossTabColumn is a column of a cross-tabulation with its total and its
+// share of the grand total
+type CrossTabColumn struct {
+	Key        string  `json:"key"`
+	Label      string  `json:"label"`
+	Total      int     `json:"total"`
+	Percentage float64 `json:"percentage"`
+}
+
+// CrossTabCell counts the pensions of a row in one column. Percentage is the
+// share of the row total.
+type CrossTabCell struct {
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+// CrossTabRow holds one cell per column, with the row total and its share of
+// the grand total
+type CrossTabRow struct {
+	Key        string         `json:"key"`
+	Label      string         `json:"label"`
+	Cells      []CrossTabCell `json:"cells"`
+	Total      int            `json:"total"`
+	Percentage float64        `json:"percentage"`
+}
+
+// CrossTab counts the pensions matching a filter for every pair of values of
+// two dimensions
+type CrossTab struct {
+	RowDimension    Dimension        `json:"row_dimension"`
+	ColumnDimension Dimension        `json:"column_dimension"`
+	Columns         []CrossTabColumn `json:"columns"`
+	Rows            []CrossTabRow    `json:"rows"`
+	Total           int              `json:"total"`
+}
@@ -0,0 +1,50 @@
+package domain
+
+// AgeBand groups pensioners younger than To years, and not in an earlier
+// band. The last band has no upper bound.
+type AgeBand struct {
+	Key string
+	To  int
+}
+
+// AgeBands are the age bands of the demographics, in ascending age
+var AgeBands = []AgeBand{
+	{Key: "<60", To: 60},
+	{Key: "60-69", To: 70},
+	{Key: "70-79", To: 80},
+	{Key: "80-89", To: 90},
+	{Key: "90+"},
+}
+
+// AgeBandUnknown is the band of records without a usable birth date
+const AgeBandUnknown = "unknown"
+
+// DemographicGroup counts the pensions sharing one value of a breakdown
+type DemographicGroup struct {
+	Key        string  `json:"key"`
+	Label      string  `json:"label"`
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+// Demographics breaks the pensions matching a filter down by sex, age band
+// and avantage category. Percentages are shares of Total.
+type Demographics struct {
+	Total     int                `json:"total"`
+	Sexe      []DemographicGroup `json:"sexe"`
+	AgeBands  []DemographicGroup `json:"age_bands"`
+	Avantages []DemographicGroup `json:"avantages"`
+}
+
+// SexeLabel returns the label of a SexeTP value
+func SexeLabel(sexe string) string {
+	switch sexe {
+	case "M":
+		return "Hommes"
+	case "F":
+		return "Femmes"
+	case "":
+		return "Inconnu"
+	}
+	return sexe
+}
@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"io"
+	"time"
+)
+
+type ImportStatus string
+
+const (
+	ImportStatusPending   ImportStatus = "pending"
+	ImportStatusRunning   ImportStatus = "running"
+	ImportStatusCompleted ImportStatus = "completed"
+	ImportStatusFailed    ImportStatus = "failed"
+	// ImportStatusRolledBack marks an import batch whose records were deleted
+	ImportStatusRolledBack ImportStatus = "rolled_back"
+)
+
+type ImportMode string
+
+const (
+	// ImportModeBestEffort inserts every valid row and skips rejected ones
+	ImportModeBestEffort ImportMode = "best_effort"
+	// ImportModeAtomic commits only if the rejection rate stays under MaxRejectRate
+	ImportModeAtomic ImportMode = "atomic"
+)
+
+type ImportFormat string
+
+const (
+	ImportFormatXLSX   ImportFormat = "xlsx"
+	ImportFormatCSV    ImportFormat = "csv"
+	ImportFormatTSV    ImportFormat = "tsv"
+	ImportFormatNDJSON ImportFormat = "ndjson"
+)
+
+// ImportOptions tunes how an import run reads its source and writes to the database. Zero or nil
+// values fall back to the importer defaults.
+type ImportOptions struct {
+	Format        ImportFormat `json:"format,omitempty"`
+	Delimiter     string       `json:"delimiter,omitempty"`
+	Encoding      string       `json:"encoding,omitempty"`
+	Mode          ImportMode   `json:"mode"`
+	BatchSize     int          `json:"batch_size"`
+	MaxRejectRate *float64     `json:"max_reject_rate,omitempty"`
+	Upsert        *bool        `json:"upsert,omitempty"`
+	UpsertKey     UpsertKey    `json:"upsert_key,omitempty"`
+	ProgressEvery int          `json:"progress_every,omitempty"`
+
+	// DateLayouts are Go time layouts tried before the built-in date formats
+	DateLayouts []string `json:"date_layouts,omitempty"`
+
+	// Sheets restricts a workbook import to the named sheets; empty means every sheet
+	Sheets []string `json:"sheets,omitempty"`
+	// SheetAsWilaya derives AG and Wilaya from the sheet name, e.g. "16 - Alger",
+	// when the sheet has no such column
+	SheetAsWilaya bool `json:"sheet_as_wilaya,omitempty"`
+
+	// Passwords are tried in turn to open an encrypted workbook
+	Passwords []string `json:"-"`
+
+	// RejectReportPath is where the rejected rows workbook is written; empty disables it
+	RejectReportPath string `json:"-"`
+	// RejectReportPassword encrypts the rejected rows workbook when set
+	RejectReportPassword string `json:"-"`
+	// FileName and UserID are recorded on the import batch. FileName is the
+	// original name of an uploaded file, which is stored under a temporary name.
+	FileName string `json:"-"`
+	UserID   *uint  `json:"-"`
+}
+
+// ImportSummary holds the row counters of an import run
+type ImportSummary struct {
+	// BatchID is the import batch recorded for the run, if any
+	BatchID uint `json:"batch_id,omitempty"`
+
+	RowsProcessed int `json:"rows_processed"`
+	RowsInserted  int `json:"rows_inserted"`
+	RowsUpdated   int `json:"rows_updated"`
+	RowsUnchanged int `json:"rows_unchanged"`
+	RowsRejected  int `json:"rows_rejected"`
+
+	// Sheets holds the counters of each imported sheet of a workbook
+	Sheets []SheetSummary `json:"sheets,omitempty"`
+}
+
+type SheetSummary struct {
+	Sheet string `json:"sheet"`
+	Error string `json:"error,omitempty"`
+	ImportSummary
+}
+
+type ImportJob struct {
+	ID         string        `json:"id"`
+	FileName   string        `json:"file_name"`
+	Status     ImportStatus  `json:"status"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	StartedAt  *time.Time    `json:"started_at,omitempty"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Options    ImportOptions `json:"options"`
+	ImportSummary
+
+	// RejectReport is the path of the rejected rows workbook, if any row was rejected
+	RejectReport    string `json:"-"`
+	HasRejectReport bool   `json:"has_reject_report"`
+}
+
+// ColumnProfile summarises the values of one mapped column in a validated file
+type ColumnProfile struct {
+	Field  string   `json:"field"`
+	Column string   `json:"column"`
+	Nulls  int      `json:"nulls"`
+	Errors int      `json:"errors"`
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+}
+
+type RejectedRow struct {
+	Sheet  string   `json:"sheet,omitempty"`
+	Row    int      `json:"row"`
+	Column string   `json:"column"`
+	Reason string   `json:"reason"`
+	Values []string `json:"values"`
+}
+
+// ValidationReport is the outcome of a dry run: nothing is written to the database
+type ValidationReport struct {
+	FileName    string          `json:"file_name"`
+	Rows        int             `json:"rows"`
+	ValidRows   int             `json:"valid_rows"`
+	InvalidRows int             `json:"invalid_rows"`
+	Columns     []ColumnProfile `json:"columns"`
+	Samples     []RejectedRow   `json:"samples"`
+}
+
+type ImportUseCase interface {
+	StartImport(fileName string, src io.Reader, opts ImportOptions) (*ImportJob, error)
+	GetImportJob(id string) (*ImportJob, error)
+	GetRejectReport(id string) (*ImportJob, error)
+	ValidateImport(fileName string, src io.Reader, opts ImportOptions) (*ValidationReport, error)
+	GetImportBatches() ([]ImportBatch, error)
+	GetImportBatch(id uint) (*ImportBatch, error)
+	RollbackImportBatch(id uint) (*ImportBatch, error)
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// ImportBatch records one import run. Every pension row it creates keeps a
+// reference to the batch, so a bad file can be traced and rolled back.
+type ImportBatch struct {
+	ID           uint         `json:"id"`
+	FileName     string       `json:"file_name"`
+	Checksum     string       `json:"checksum" gorm:"size:64;index"`
+	UserID       *uint        `json:"user_id,omitempty"`
+	Status       ImportStatus `json:"status" gorm:"size:16"`
+	Error        string       `json:"error,omitempty"`
+	StartedAt    time.Time    `json:"started_at"`
+	FinishedAt   *time.Time   `json:"finished_at,omitempty"`
+	RolledBackAt *time.Time   `json:"rolled_back_at,omitempty"`
+
+	RowsProcessed int `json:"rows_processed"`
+	RowsInserted  int `json:"rows_inserted"`
+	RowsUpdated   int `json:"rows_updated"`
+	RowsUnchanged int `json:"rows_unchanged"`
+	RowsRejected  int `json:"rows_rejected"`
+	// RowsDeleted counts the records removed by the rollback
+	RowsDeleted int `json:"rows_deleted,omitempty"`
+}
+
+type ImportBatchRepository interface {
+	Create(batch *ImportBatch) error
+	Update(batch *ImportBatch) error
+	FindByID(id uint) (*ImportBatch, error)
+	FindAll() ([]ImportBatch, error)
+	// Rollback deletes the pension records created by the batch and marks it rolled back
+	Rollback(batch *ImportBatch) error
+}
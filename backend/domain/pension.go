@@ -4,41 +4,123 @@ import "time"
 
 type PensionData struct {
 	ID                 uint      `json:"id"`
-	AG                 int8      `json:"ag"`
+	AG                 int8      `json:"ag" gorm:"index;uniqueIndex:uniq_pension_natural_key,priority:2"`
 	AVT                string    `json:"avt"`
-	NPens              string    `json:"npens"`
+	NPens              string    `json:"npens" gorm:"size:64;uniqueIndex:uniq_pension_natural_key,priority:1"`
 	EtatPens           string    `json:"etatpens"`
-	DateNais           time.Time `json:"datenais"`
-	DateJouis          time.Time `json:"datjouis"`
+	DateNais           time.Time `json:"datenais" gorm:"index"`
+	DateJouis          time.Time `json:"datjouis" gorm:"index"`
 	SexeTP             string    `json:"sexe_tp"`
-	NetMens            float64   `json:"net_mens"`
-	TauxD              float64   `json:"taux_d"`
-	TauxRV             float64   `json:"taux_rv"`
-	TauxGLB            float64   `json:"taux_glb"`
-	AgeAppTP           int8      `json:"age_app_tp"`
-	DureePension       int       `json:"duree_pension"`
-	AgeMoyenCat        int8      `json:"age_moyen_cat"`
-	RisqueAge          int8      `json:"risque_age"`
+	NetMens            float64   `json:"net_mens" gorm:"index"`
+	TauxD              float64   `json:"taux_d" gorm:"index"`
+	TauxRV             float64   `json:"taux_rv" gorm:"index"`
+	TauxGLB            float64   `json:"taux_glb" gorm:"index"`
+	AgeAppTP           int8      `json:"age_app_tp" gorm:"index"`
+	DureePension       int       `json:"duree_pension" gorm:"index"`
+	AgeMoyenCat        int8      `json:"age_moyen_cat" gorm:"index"`
+	RisqueAge          int8      `json:"risque_age" gorm:"index"`
 	NiveauRisquePredit int8      `json:"niveau_risque_predit"`
 	Wilaya             string    `json:"wilaya"`
+
+	// Import provenance: the batch that created the record and its row in the source file
+	ImportBatchID *uint  `json:"import_batch_id,omitempty" gorm:"index"`
+	SourceSheet   string `json:"source_sheet,omitempty" gorm:"size:64"`
+	SourceRow     int    `json:"source_row,omitempty"`
+}
+
+// PensionColumns maps the JSON name of each PensionData field to its column.
+// These are the names accepted by the sort and fields list parameters.
+var PensionColumns = map[string]string{
+	"id":                   "id",
+	"ag":                   "ag",
+	"avt":                  "avt",
+	"npens":                "n_pens",
+	"etatpens":             "etat_pens",
+	"datenais":             "date_nais",
+	"datjouis":             "date_jouis",
+	"sexe_tp":              "sexe_tp",
+	"net_mens":             "net_mens",
+	"taux_d":               "taux_d",
+	"taux_rv":              "taux_rv",
+	"taux_glb":             "taux_glb",
+	"age_app_tp":           "age_app_tp",
+	"duree_pension":        "duree_pension",
+	"age_moyen_cat":        "age_moyen_cat",
+	"risque_age":           "risque_age",
+	"niveau_risque_predit": "niveau_risque_predit",
+	"wilaya":               "wilaya",
+	"import_batch_id":      "import_batch_id",
+	"source_sheet":         "source_sheet",
+	"source_row":           "source_row",
+}
+
+// SortField orders a list on one field, named as in PensionColumns
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// PensionQuery selects a page of the pensions matching Filter. A zero Limit
+// returns every matching record. Fields restricts the loaded columns, all
+// when empty.
+type PensionQuery struct {
+	Filter PensionFilter
+	Page   int
+	Limit  int
+	Sort   []SortField
+	Fields []string
+}
+
+// UpsertKey selects the natural key used to match imported pensions against
+// existing records
+type UpsertKey string
+
+const (
+	UpsertKeyNPens   UpsertKey = "npens"
+	UpsertKeyNPensAG UpsertKey = "npens_ag"
+)
+
+// UpsertResult counts the records an upsert inserted, updated and left as they
+// were. Rows repeating a key of the same batch count as unchanged.
+type UpsertResult struct {
+	Inserted  int
+	Updated   int
+	Unchanged int
 }
 
 type PensionRepository interface {
 	Create(pension *PensionData) error
+	CreateBatch(pensions []PensionData, batchSize int) error
+	UpsertBatch(pensions []PensionData, key UpsertKey, batchSize int) (UpsertResult, error)
 	FindByID(id uint) (*PensionData, error)
-	FindAll(avantages []string) ([]PensionData, int64, error)
+	FindAll(query PensionQuery) ([]PensionData, int64, error)
 	Update(pension *PensionData) error
 	Delete(id uint) error
-	GetRiskLevelStats(wilaya string, categories []string, avantages []string) ([]RiskLevelStats, error)
+	GetRiskLevelStats(filter PensionFilter) ([]RiskLevelStats, error)
+	GetWilayaStats(filter PensionFilter) ([]WilayaStats, error)
+	GetDemographics(filter PensionFilter) (*Demographics, error)
+	GetCrossTab(filter PensionFilter, rows, columns Dimension) ([]CrossTabCount, error)
+	Pivot(query PivotQuery) (*PivotResult, error)
+	// ForEach calls fn for every record matching the filter, reading batchSize rows at a time
+	ForEach(filter PensionFilter, batchSize int, fn func(pension *PensionData) error) error
+	Transaction(fn func(repo PensionRepository) error) error
 }
 
 type PensionUseCase interface {
 	CreatePension(pension *PensionData) error
+	CreatePensions(pensions []PensionData, batchSize int) error
+	UpsertPensions(pensions []PensionData, key UpsertKey, batchSize int) (UpsertResult, error)
 	GetPension(id uint) (*PensionData, error)
-	GetAllPensions(avantages []string) ([]PensionData, int64, error)
+	GetAllPensions(query PensionQuery) ([]PensionData, int64, error)
 	UpdatePension(pension *PensionData) error
 	DeletePension(id uint) error
-	GetRiskLevelStats(wilaya string, categories []string, avantages []string) ([]RiskLevelStats, error)
+	GetRiskLevelStats(filter PensionFilter) ([]RiskLevelStats, error)
+	GetWilayaStats(filter PensionFilter) ([]WilayaStats, error)
+	GetDemographics(filter PensionFilter) (*Demographics, error)
+	GetCrossTab(filter PensionFilter, rows, columns Dimension) (*CrossTab, error)
+	Pivot(query PivotQuery) (*PivotResult, error)
+	ExportPensions(filter PensionFilter, fn func(pension *PensionData) error) error
+	Transaction(fn func(uc PensionUseCase) error) error
 }
 
 type RiskLevelStats struct {
@@ -46,3 +128,11 @@ type RiskLevelStats struct {
 	Count      int     `json:"count"`
 	Percentage float64 `json:"percentage"`
 }
+
+// WilayaStats counts the pensions of one agency
+type WilayaStats struct {
+	AG         int8    `json:"ag"`
+	Wilaya     string  `json:"wilaya"`
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DateLayout is the layout of the dates accepted by filters
+const DateLayout = "2006-01-02"
+
+// Date is a calendar date written as 2006-01-02
+type Date struct {
+	time.Time
+}
+
+// ParseDate parses a date in DateLayout
+func ParseDate(value string) (Date, error) {
+	t, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+	}
+	return Date{t}, nil
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Format(DateLayout))
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := ParseDate(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Range bounds a numeric field. Bounds are inclusive and nil bounds are open.
+type Range struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// DateRange bounds a date field. After and Before are exclusive and nil
+// bounds are open.
+type DateRange struct {
+	After  *Date `json:"after,omitempty"`
+	Before *Date `json:"before,omitempty"`
+}
+
+// PensionFilter selects pensions. It is shared by the list, the statistics
+// and the exports, and every condition set must hold.
+type PensionFilter struct {
+	// Wilaya is a wilaya code or name, selecting the records of its agencies
+	Wilaya string `json:"wilaya"`
+	// Agency is an agency code (AG) or name
+	Agency string `json:"agency"`
+	// Categories are pension states (EtatPens) or pension category names, which
+	// match the AgeMoyenCat codes the reference tables assign to them. A record
+	// matches when either applies.
+	Categories []string `json:"categories"`
+	// Avantages are benefit group names, AvantageEmpty, or AVT codes for any other value
+	Avantages  []string `json:"avantages"`
+	Sexe       []string `json:"sexe"`
+	RiskLevels []int8   `json:"risk_levels"`
+
+	AG           Range     `json:"ag"`
+	NetMens      Range     `json:"net_mens"`
+	TauxD        Range     `json:"taux_d"`
+	TauxRV       Range     `json:"taux_rv"`
+	TauxGLB      Range     `json:"taux_glb"`
+	AgeAppTP     Range     `json:"age_app_tp"`
+	DureePension Range     `json:"duree_pension"`
+	AgeMoyenCat  Range     `json:"age_moyen_cat"`
+	RisqueAge    Range     `json:"risque_age"`
+	DateNais     DateRange `json:"datenais"`
+	DateJouis    DateRange `json:"datjouis"`
+}
+
+// NamedRange is a numeric range of a filter with the JSON name of its field
+type NamedRange struct {
+	Field string
+	Range *Range
+}
+
+// NamedDateRange is a date range of a filter with the JSON name of its field
+type NamedDateRange struct {
+	Field string
+	Range *DateRange
+}
+
+// NumericRanges lists the numeric ranges of the filter
+func (f *PensionFilter) NumericRanges() []NamedRange {
+	return []NamedRange{
+		{"ag", &f.AG},
+		{"net_mens", &f.NetMens},
+		{"taux_d", &f.TauxD},
+		{"taux_rv", &f.TauxRV},
+		{"taux_glb", &f.TauxGLB},
+		{"age_app_tp", &f.AgeAppTP},
+		{"duree_pension", &f.DureePension},
+		{"age_moyen_cat", &f.AgeMoyenCat},
+		{"risque_age", &f.RisqueAge},
+	}
+}
+
+// DateRanges lists the date ranges of the filter
+func (f *PensionFilter) DateRanges() []NamedDateRange {
+	return []NamedDateRange{
+		{"datenais", &f.DateNais},
+		{"datjouis", &f.DateJouis},
+	}
+}
+
+// Validate checks the values of the filter and that every range can match
+func (f *PensionFilter) Validate() error {
+	for _, level := range f.RiskLevels {
+		if level < 0 || level > 2 {
+			return fmt.Errorf("invalid risk level %d, expected 0, 1 or 2", level)
+		}
+	}
+
+	for _, r := range f.NumericRanges() {
+		for _, bound := range []*float64{r.Range.Min, r.Range.Max} {
+			if bound != nil && (math.IsNaN(*bound) || math.IsInf(*bound, 0)) {
+				return fmt.Errorf("%s bounds must be finite numbers", r.Field)
+			}
+		}
+		if r.Range.Min != nil && r.Range.Max != nil && *r.Range.Min > *r.Range.Max {
+			return fmt.Errorf("%s minimum is greater than its maximum", r.Field)
+		}
+	}
+
+	for _, r := range f.DateRanges() {
+		for _, bound := range []*Date{r.Range.After, r.Range.Before} {
+			if bound != nil && (bound.Year() < minFilterYear || bound.Year() > maxFilterYear) {
+				return fmt.Errorf("%s bounds must be between %d and %d", r.Field, minFilterYear, maxFilterYear)
+			}
+		}
+		if r.Range.After != nil && r.Range.Before != nil && !r.Range.After.Before(r.Range.Before.Time) {
+			return fmt.Errorf("%s after date must precede its before date", r.Field)
+		}
+	}
+
+	return nil
+}
+
+// Dates outside these years are typing mistakes rather than pension dates
+const (
+	minFilterYear = 1800
+	maxFilterYear = 2200
+)
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DimensionAVT groups on the AVT code itself
+	DimensionAVT Dimension = "avt"
+	// DimensionBirthYearBand groups on the decade of DateNais
+	DimensionBirthYearBand Dimension = "birth_year_band"
+	// DimensionJouissanceYear groups on the year of DateJouis
+	DimensionJouissanceYear Dimension = "jouissance_year"
+)
+
+// PivotDimensions are the dimensions a pivot accepts
+var PivotDimensions = []Dimension{
+	DimensionAgency, DimensionAVT, DimensionAvantage, DimensionEtatPens, DimensionSexe,
+	DimensionRiskLevel, DimensionBirthYearBand, DimensionJouissanceYear,
+}
+
+// MeasureFunc is the aggregate of a pivot measure
+type MeasureFunc string
+
+const (
+	MeasureCount      MeasureFunc = "count"
+	MeasureSum        MeasureFunc = "sum"
+	MeasureAvg        MeasureFunc = "avg"
+	MeasureMin        MeasureFunc = "min"
+	MeasureMax        MeasureFunc = "max"
+	MeasurePercentile MeasureFunc = "percentile"
+)
+
+// MeasureFields are the fields measures aggregate, by JSON name
+var MeasureFields = []string{"net_mens", "taux_glb", "duree_pension"}
+
+// Measure is an aggregate computed for every group of a pivot. Field is
+// empty for counts, and Percentile is set for percentiles only.
+type Measure struct {
+	Func       MeasureFunc
+	Field      string
+	Percentile int
+}
+
+// ParseMeasure reads a measure written count, <function>:<field> with sum,
+// avg, min or max, or p<1-99>:<field> for a percentile
+func ParseMeasure(value string) (Measure, error) {
+	value = strings.TrimSpace(value)
+	if value == string(MeasureCount) {
+		return Measure{Func: MeasureCount}, nil
+	}
+
+	fn, field, ok := strings.Cut(value, ":")
+	if !ok {
+		return Measure{}, fmt.Errorf("invalid measure %q, expected count or <function>:<field>", value)
+	}
+
+	m := Measure{Field: field}
+	switch MeasureFunc(fn) {
+	case MeasureSum, MeasureAvg, MeasureMin, MeasureMax:
+		m.Func = MeasureFunc(fn)
+	default:
+		p, err := strconv.Atoi(strings.TrimPrefix(fn, "p"))
+		if !strings.HasPrefix(fn, "p") || err != nil || p < 1 || p > 99 {
+			return Measure{}, fmt.Errorf("unknown measure function %q, expected sum, avg, min, max or p1 to p99", fn)
+		}
+		m.Func, m.Percentile = MeasurePercentile, p
+	}
+
+	for _, f := range MeasureFields {
+		if f == field {
+			return m, nil
+		}
+	}
+	return Measure{}, fmt.Errorf("cannot measure field %q, expected one of %s", field, strings.Join(MeasureFields, ", "))
+}
+
+// Name is the column name of the measure in pivot results, such as count,
+// avg_net_mens or p90_duree_pension
+func (m Measure) Name() string {
+	switch m.Func {
+	case MeasureCount:
+		return string(MeasureCount)
+	case MeasurePercentile:
+		return fmt.Sprintf("p%d_%s", m.Percentile, m.Field)
+	}
+	return string(m.Func) + "_" + m.Field
+}
+
+// PivotQuery groups the pensions matching Filter on Dimensions, in that
+// order, and computes Measures for every group. Without dimensions a single
+// row covers every matching pension.
+type PivotQuery struct {
+	Filter     PensionFilter
+	Dimensions []Dimension
+	Measures   []Measure
+}
+
+// Kinds of pivot columns
+const (
+	PivotColumnDimension = "dimension"
+	PivotColumnLabel     = "label"
+	PivotColumnMeasure   = "measure"
+)
+
+// PivotColumn describes a column of a pivot result
+type PivotColumn struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// PivotResult is a table with the key and the label of every dimension,
+// then every measure. Measures of groups without values are null.
+type PivotResult struct {
+	Columns []PivotColumn   `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
@@ -0,0 +1,210 @@
+package domain
+
+import "errors"
+
+var (
+	ErrReferenceNotFound = errors.New("reference entry not found")
+	ErrInvalidReference  = errors.New("invalid reference entry")
+)
+
+// AvantageEmpty is the avantages filter value selecting records without an AVT code
+const AvantageEmpty = "(Vide)"
+
+// AvantageCategory gathers the AVT codes of one kind of benefit. Name is the
+// value accepted by the avantages filters.
+type AvantageCategory struct {
+	Name     string   `json:"name" gorm:"primaryKey;size:32"`
+	Label    string   `json:"label"`
+	Position int      `json:"position"`
+	Codes    []string `json:"codes" gorm:"-"`
+}
+
+// AvantageCode assigns an AVT code to its category
+type AvantageCode struct {
+	Code     string `gorm:"primaryKey;size:8"`
+	Category string `gorm:"size:32;index"`
+}
+
+// PensionCategory gathers the AgeMoyenCat values of one kind of pension. Name
+// is accepted by the categories filters next to pension states.
+type PensionCategory struct {
+	Name         string `json:"name" gorm:"primaryKey;size:32"`
+	Label        string `json:"label"`
+	Position     int    `json:"position"`
+	AgeMoyenCats []int8 `json:"age_moyen_cats" gorm:"-"`
+}
+
+// PensionCategoryCode assigns an AgeMoyenCat value to its category
+type PensionCategoryCode struct {
+	AgeMoyenCat int8   `gorm:"primaryKey;autoIncrement:false"`
+	Category    string `gorm:"size:32;index"`
+}
+
+// Wilaya is an administrative province with its official code
+type Wilaya struct {
+	Code int    `json:"code" gorm:"primaryKey;autoIncrement:false"`
+	Name string `json:"name" gorm:"size:64"`
+}
+
+// Agency is a CNR agency, identified in pension files by its AG code
+type Agency struct {
+	Code       int8   `json:"code" gorm:"primaryKey;autoIncrement:false"`
+	Name       string `json:"name" gorm:"size:64"`
+	WilayaCode int    `json:"wilaya_code" gorm:"index"`
+}
+
+// Commune is a municipality of a wilaya
+type Commune struct {
+	Code       int    `json:"code" gorm:"primaryKey;autoIncrement:false"`
+	Name       string `json:"name" gorm:"size:64"`
+	WilayaCode int    `json:"wilaya_code" gorm:"index"`
+}
+
+// RiskLevel labels a NiveauRisquePredit value
+type RiskLevel struct {
+	Level int8   `json:"level" gorm:"primaryKey;autoIncrement:false"`
+	Label string `json:"label" gorm:"size:32"`
+}
+
+// ReferenceData holds the code lists used to label and filter pensions
+type ReferenceData struct {
+	AvantageCategories []AvantageCategory `json:"avantage_categories"`
+	PensionCategories  []PensionCategory  `json:"pension_categories"`
+	Wilayas            []Wilaya           `json:"wilayas"`
+	Agencies           []Agency           `json:"agencies"`
+	Communes           []Commune          `json:"communes"`
+	RiskLevels         []RiskLevel        `json:"risk_levels"`
+}
+
+// Wilaya returns the wilaya with the given code
+func (r *ReferenceData) Wilaya(code int) (Wilaya, bool) {
+	for _, w := range r.Wilayas {
+		if w.Code == code {
+			return w, true
+		}
+	}
+	return Wilaya{}, false
+}
+
+// Agency returns the agency with the given AG code
+func (r *ReferenceData) Agency(code int8) (Agency, bool) {
+	for _, a := range r.Agencies {
+		if a.Code == code {
+			return a, true
+		}
+	}
+	return Agency{}, false
+}
+
+// AvantageLabel returns the label of the category holding an AVT code, or
+// the code itself when no category holds it
+func (r *ReferenceData) AvantageLabel(code string) string {
+	if code == "" || code == "0" {
+		return AvantageEmpty
+	}
+	for _, category := range r.AvantageCategories {
+		for _, c := range category.Codes {
+			if c == code {
+				return category.Label
+			}
+		}
+	}
+	return code
+}
+
+// RiskLevelLabel returns the label of a NiveauRisquePredit value
+func (r *ReferenceData) RiskLevelLabel(level int8) string {
+	for _, l := range r.RiskLevels {
+		if l.Level == level {
+			return l.Label
+		}
+	}
+	return "Unknown Risk"
+}
+
+// DefaultReferenceData returns the code lists the reference tables are
+// seeded with
+func DefaultReferenceData() *ReferenceData {
+	return &ReferenceData{
+		AvantageCategories: []AvantageCategory{
+			{Name: "direct", Label: "Direct", Position: 1, Codes: []string{"1", "7", "W", "Z", "4", "9", "G", "5"}},
+			{Name: "fille majeur", Label: "Fille majeure", Position: 2, Codes: []string{"H", "D", "Y"}},
+			{Name: "Veuves", Label: "Veuves", Position: 3, Codes: []string{"3", "2", "F", "E", "8", "J"}},
+		},
+		PensionCategories: []PensionCategory{
+			{Name: "décès", Label: "Décès", Position: 1, AgeMoyenCats: []int8{79, 77}},
+			{Name: "fin droit", Label: "Fin de droit", Position: 2, AgeMoyenCats: []int8{33, 48}},
+			{Name: "révision", Label: "Révision", Position: 3, AgeMoyenCats: []int8{64, 68, 72, 74, 75}},
+		},
+		Wilayas:  defaultWilayas(),
+		Agencies: defaultAgencies(),
+		Communes: []Commune{},
+		RiskLevels: []RiskLevel{
+			{Level: 0, Label: "Bas risque"},
+			{Level: 1, Label: "Moyen risque"},
+			{Level: 2, Label: "Haut risque"},
+		},
+	}
+}
+
+var wilayaNames = []string{
+	"Adrar", "Chlef", "Laghouat", "Oum El Bouaghi", "Batna", "Béjaïa", "Biskra", "Béchar",
+	"Blida", "Bouira", "Tamanrasset", "Tébessa", "Tlemcen", "Tiaret", "Tizi Ouzou", "Alger",
+	"Djelfa", "Jijel", "Sétif", "Saïda", "Skikda", "Sidi Bel Abbès", "Annaba", "Guelma",
+	"Constantine", "Médéa", "Mostaganem", "M'Sila", "Mascara", "Ouargla", "Oran", "El Bayadh",
+	"Illizi", "Bordj Bou Arréridj", "Boumerdès", "El Tarf", "Tindouf", "Tissemsilt", "El Oued", "Khenchela",
+	"Souk Ahras", "Tipaza", "Mila", "Aïn Defla", "Naâma", "Aïn Témouchent", "Ghardaïa", "Relizane",
+	"El M'Ghair", "El Meniaa", "Ouled Djellal", "Bordj Badji Mokhtar", "Béni Abbès", "Timimoun", "Touggourt", "Djanet",
+	"In Salah", "In Guezzam",
+}
+
+// defaultWilayas lists the 58 wilayas with their official codes
+func defaultWilayas() []Wilaya {
+	wilayas := make([]Wilaya, len(wilayaNames))
+	for i, name := range wilayaNames {
+		wilayas[i] = Wilaya{Code: i + 1, Name: name}
+	}
+	return wilayas
+}
+
+// defaultAgencies lists one agency per wilaya, coded as its wilaya, and the
+// second Alger agency used by the dashboard map
+func defaultAgencies() []Agency {
+	agencies := make([]Agency, 0, len(wilayaNames)+1)
+	for i, name := range wilayaNames {
+		agencies = append(agencies, Agency{Code: int8(i + 1), Name: name, WilayaCode: i + 1})
+	}
+	return append(agencies, Agency{Code: 95, Name: "Alger", WilayaCode: 16})
+}
+
+type ReferenceRepository interface {
+	Get() (*ReferenceData, error)
+	// SaveAvantageCategory creates or replaces a category and its codes
+	SaveAvantageCategory(category *AvantageCategory) error
+	DeleteAvantageCategory(name string) error
+	// SavePensionCategory creates or replaces a category and its AgeMoyenCat values
+	SavePensionCategory(category *PensionCategory) error
+	DeletePensionCategory(name string) error
+	SaveWilaya(wilaya *Wilaya) error
+	DeleteWilaya(code int) error
+	SaveAgency(agency *Agency) error
+	DeleteAgency(code int8) error
+	SaveCommune(commune *Commune) error
+	DeleteCommune(code int) error
+	SaveRiskLevel(level *RiskLevel) error
+}
+
+type ReferenceUseCase interface {
+	GetReferenceData() (*ReferenceData, error)
+	SaveAvantageCategory(category *AvantageCategory) error
+	DeleteAvantageCategory(name string) error
+	SavePensionCategory(category *PensionCategory) error
+	DeletePensionCategory(name string) error
+	SaveWilaya(wilaya *Wilaya) error
+	DeleteWilaya(code int) error
+	SaveAgency(agency *Agency) error
+	DeleteAgency(code int8) error
+	SaveCommune(commune *Commune) error
+	DeleteCommune(code int) error
+	SaveRiskLevel(level *RiskLevel) error
+}
@@ -0,0 +1,218 @@
+package exporter
+
+import (
+	"cnr-tp/domain"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	pensionsSheet    = "Pensions"
+	riskSummarySheet = "Risk summary"
+
+	// MaxWorkbookRecords is the number of records that fit on the pensions
+	// sheet: Excel's 1,048,576 rows less the header
+	MaxWorkbookRecords = excelize.TotalRows - 1
+)
+
+// ErrTooManyRecords is returned when the records matching the filters do not
+// fit on one sheet, the CSV export has no limit
+var ErrTooManyRecords = errors.New("too many records for an Excel export")
+
+// Exporter writes filtered pension records to files for analysts
+type Exporter struct {
+	pensionUseCase domain.PensionUseCase
+	// referenceUseCase provides the labels of the codes, the defaults are used when nil
+	referenceUseCase domain.ReferenceUseCase
+	// password encrypts the Excel workbooks when set
+	password string
+}
+
+func New(pensionUseCase domain.PensionUseCase, referenceUseCase domain.ReferenceUseCase, password string) *Exporter {
+	return &Exporter{pensionUseCase: pensionUseCase, referenceUseCase: referenceUseCase, password: password}
+}
+
+// referenceData loads the code labels once per export
+func (e *Exporter) referenceData() (*domain.ReferenceData, error) {
+	if e.referenceUseCase == nil {
+		return domain.DefaultReferenceData(), nil
+	}
+	ref, err := e.referenceUseCase.GetReferenceData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reference data: %v", err)
+	}
+	return ref, nil
+}
+
+// columnKind selects the cell style of a column
+type columnKind int
+
+const (
+	kindText columnKind = iota
+	kindInt
+	kindAmount
+	kindRate
+	kindDate
+)
+
+type pensionColumn struct {
+	header string
+	kind   columnKind
+	value  func(p *domain.PensionData, ref *domain.ReferenceData) interface{}
+}
+
+var pensionColumns = []pensionColumn{
+	{"ID", kindInt, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.ID }},
+	{"AG", kindInt, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.AG }},
+	{"Wilaya", kindText, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.Wilaya }},
+	{"AVT", kindText, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.AVT }},
+	{"Avantage", kindText, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return ref.AvantageLabel(p.AVT) }},
+	{"NPens", kindText, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.NPens }},
+	{"Etat Pension", kindText, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.EtatPens }},
+	{"Date de naissance", kindDate, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.DateNais }},
+	{"Date de jouissance", kindDate, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.DateJouis }},
+	{"Sexe", kindText, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.SexeTP }},
+	{"Net Mensuel", kindAmount, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.NetMens }},
+	{"Taux D", kindRate, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.TauxD }},
+	{"Taux RV", kindRate, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.TauxRV }},
+	{"Taux Global", kindRate, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.TauxGLB }},
+	{"Age App TP", kindInt, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.AgeAppTP }},
+	{"Duree Pension", kindInt, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.DureePension }},
+	{"Age Moyen Cat", kindInt, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.AgeMoyenCat }},
+	{"Risque Age", kindInt, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.RisqueAge }},
+	{"Niveau Risque Predit", kindInt, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} { return p.NiveauRisquePredit }},
+	{"Niveau de risque", kindText, func(p *domain.PensionData, ref *domain.ReferenceData) interface{} {
+		return ref.RiskLevelLabel(p.NiveauRisquePredit)
+	}},
+}
+
+// PensionWorkbook builds a workbook with the records matching the filters on
+// a first sheet and their risk level summary on a second one. Records are
+// streamed from the database to temporary storage rather than held in memory.
+// ErrTooManyRecords is returned before any record is read when they would not
+// fit on the sheet. The caller must close the workbook.
+func (e *Exporter) PensionWorkbook(filter domain.PensionFilter) (*excelize.File, error) {
+	f := excelize.NewFile()
+	if err := e.buildPensionWorkbook(f, filter); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write saves the workbook to w, encrypted if the exporter has a password
+func (e *Exporter) Write(f *excelize.File, w io.Writer) error {
+	return f.Write(w, excelize.Options{Password: e.password})
+}
+
+func (e *Exporter) buildPensionWorkbook(f *excelize.File, filter domain.PensionFilter) error {
+	// The risk summary counts the matching records, so it is computed first
+	// to refuse exports that Excel would truncate
+	stats, err := e.pensionUseCase.GetRiskLevelStats(filter)
+	if err != nil {
+		return fmt.Errorf("failed to compute risk summary: %v", err)
+	}
+	total := 0
+	for _, s := range stats {
+		total += s.Count
+	}
+	if total > MaxWorkbookRecords {
+		return fmt.Errorf("%w: %d records match the filters, a sheet holds at most %d; use export.csv instead",
+			ErrTooManyRecords, total, MaxWorkbookRecords)
+	}
+
+	if err := f.SetSheetName(f.GetSheetName(0), pensionsSheet); err != nil {
+		return err
+	}
+
+	styles, err := newStyles(f)
+	if err != nil {
+		return err
+	}
+	ref, err := e.referenceData()
+	if err != nil {
+		return err
+	}
+
+	sw, err := f.NewStreamWriter(pensionsSheet)
+	if err != nil {
+		return err
+	}
+	if err := sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+	if err := sw.SetColWidth(1, len(pensionColumns), 16); err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(pensionColumns))
+	for i, col := range pensionColumns {
+		header[i] = excelize.Cell{StyleID: styles.header, Value: col.header}
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	rowNum := 2
+	err = e.pensionUseCase.ExportPensions(filter, func(p *domain.PensionData) error {
+		row := make([]interface{}, len(pensionColumns))
+		for i, col := range pensionColumns {
+			row[i] = styles.cell(col.kind, col.value(p, ref))
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, rowNum)
+		rowNum++
+		return sw.SetRow(cell, row)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export pensions: %v", err)
+	}
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+
+	return writeRiskSummary(f, styles, stats)
+}
+
+// writeRiskSummary adds the count and share of each risk level, with a total row
+func writeRiskSummary(f *excelize.File, styles *styles, stats []domain.RiskLevelStats) error {
+	if _, err := f.NewSheet(riskSummarySheet); err != nil {
+		return err
+	}
+	sw, err := f.NewStreamWriter(riskSummarySheet)
+	if err != nil {
+		return err
+	}
+	if err := sw.SetColWidth(1, 3, 18); err != nil {
+		return err
+	}
+
+	rows := [][]interface{}{{
+		excelize.Cell{StyleID: styles.header, Value: "Niveau de risque"},
+		excelize.Cell{StyleID: styles.header, Value: "Nombre"},
+		excelize.Cell{StyleID: styles.header, Value: "Pourcentage"},
+	}}
+	total := 0
+	for _, s := range stats {
+		total += s.Count
+		rows = append(rows, []interface{}{
+			s.RiskLevel,
+			s.Count,
+			excelize.Cell{StyleID: styles.percent, Value: s.Percentage / 100},
+		})
+	}
+	rows = append(rows, []interface{}{
+		excelize.Cell{StyleID: styles.header, Value: "Total"},
+		excelize.Cell{StyleID: styles.header, Value: total},
+	})
+
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := sw.SetRow(cell, row); err != nil {
+			return err
+		}
+	}
+	return sw.Flush()
+}
@@ -0,0 +1,259 @@
+package exporter
+
+import (
+	"cnr-tp/domain"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	reportSummarySheet = "Summary"
+	reportWilayaSheet  = "Wilayas"
+)
+
+// RiskReport builds the management risk report: a summary sheet with the risk
+// level breakdown and a pie chart, and a sheet with the per-wilaya counts and
+// a bar chart. Both sheets start with a description of the filters. The
+// caller must close the workbook.
+func (e *Exporter) RiskReport(filter domain.PensionFilter) (*excelize.File, error) {
+	stats, err := e.pensionUseCase.GetRiskLevelStats(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute risk levels: %v", err)
+	}
+	wilayas, err := e.pensionUseCase.GetWilayaStats(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute wilaya breakdown: %v", err)
+	}
+
+	ref, err := e.referenceData()
+	if err != nil {
+		return nil, err
+	}
+
+	f := excelize.NewFile()
+	if err := buildRiskReport(f, filterDescription(filter, ref), stats, wilayas); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func buildRiskReport(f *excelize.File, filters [][2]string, stats []domain.RiskLevelStats, wilayas []domain.WilayaStats) error {
+	if err := f.SetSheetName(f.GetSheetName(0), reportSummarySheet); err != nil {
+		return err
+	}
+	if _, err := f.NewSheet(reportWilayaSheet); err != nil {
+		return err
+	}
+
+	styles, err := newStyles(f)
+	if err != nil {
+		return err
+	}
+	title, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	if err != nil {
+		return err
+	}
+
+	// Risk level summary
+	sheet := reportSummarySheet
+	row, err := writeReportHeader(f, sheet, "Rapport de risque des pensions", filters, title, styles)
+	if err != nil {
+		return err
+	}
+	rows := [][]interface{}{{"Niveau de risque", "Nombre", "Pourcentage"}}
+	total := 0
+	for _, s := range stats {
+		total += s.Count
+		rows = append(rows, []interface{}{s.RiskLevel, s.Count, s.Percentage / 100})
+	}
+	rows = append(rows, []interface{}{"Total", total, nil})
+	if err := writeTable(f, sheet, row, rows, styles, map[int]int{2: styles.percent}); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(sheet, "A", "C", 20); err != nil {
+		return err
+	}
+	if len(stats) > 0 {
+		first, last := row+1, row+len(stats)
+		err := f.AddChart(sheet, "E"+strconv.Itoa(row), &excelize.Chart{
+			Type: excelize.Pie,
+			Series: []excelize.ChartSeries{{
+				Name:       fmt.Sprintf("'%s'!$B$%d", sheet, row),
+				Categories: fmt.Sprintf("'%s'!$A$%d:$A$%d", sheet, first, last),
+				Values:     fmt.Sprintf("'%s'!$B$%d:$B$%d", sheet, first, last),
+			}},
+			Title:     []excelize.RichTextRun{{Text: "Répartition par niveau de risque"}},
+			Legend:    excelize.ChartLegend{Position: "right"},
+			PlotArea:  excelize.ChartPlotArea{ShowPercent: true},
+			Dimension: excelize.ChartDimension{Width: 480, Height: 320},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add risk chart: %v", err)
+		}
+	}
+
+	// Per-wilaya breakdown
+	sheet = reportWilayaSheet
+	row, err = writeReportHeader(f, sheet, "Pensions par wilaya", filters, title, styles)
+	if err != nil {
+		return err
+	}
+	rows = [][]interface{}{{"AG", "Wilaya", "Nombre", "Pourcentage"}}
+	for _, w := range wilayas {
+		name := w.Wilaya
+		if name == "" {
+			name = "AG " + strconv.Itoa(int(w.AG))
+		}
+		rows = append(rows, []interface{}{w.AG, name, w.Count, w.Percentage / 100})
+	}
+	if err := writeTable(f, sheet, row, rows, styles, map[int]int{3: styles.percent}); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(sheet, "A", "D", 20); err != nil {
+		return err
+	}
+	if len(wilayas) > 0 {
+		first, last := row+1, row+len(wilayas)
+		err := f.AddChart(sheet, "F"+strconv.Itoa(row), &excelize.Chart{
+			Type: excelize.Bar,
+			Series: []excelize.ChartSeries{{
+				Name:       fmt.Sprintf("'%s'!$C$%d", sheet, row),
+				Categories: fmt.Sprintf("'%s'!$B$%d:$B$%d", sheet, first, last),
+				Values:     fmt.Sprintf("'%s'!$C$%d:$C$%d", sheet, first, last),
+			}},
+			Title:  []excelize.RichTextRun{{Text: "Pensions par wilaya"}},
+			Legend: excelize.ChartLegend{Position: "none"},
+			// List the wilayas top to bottom in table order
+			XAxis:     excelize.ChartAxis{ReverseOrder: true},
+			PlotArea:  excelize.ChartPlotArea{ShowVal: true},
+			Dimension: excelize.ChartDimension{Width: 560, Height: uint(max(320, 22*len(wilayas)))},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add wilaya chart: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// writeReportHeader writes the title, generation time and filters of a report
+// sheet and returns the first free row after them
+func writeReportHeader(f *excelize.File, sheet, title string, filters [][2]string, titleStyle int, styles *styles) (int, error) {
+	if err := f.SetCellValue(sheet, "A1", title); err != nil {
+		return 0, err
+	}
+	if err := f.SetCellStyle(sheet, "A1", "A1", titleStyle); err != nil {
+		return 0, err
+	}
+	if err := f.SetCellValue(sheet, "A2", "Généré le "+time.Now().Format("02/01/2006 15:04")); err != nil {
+		return 0, err
+	}
+
+	row := 4
+	if err := f.SetCellValue(sheet, "A4", "Filtres"); err != nil {
+		return 0, err
+	}
+	if err := f.SetCellStyle(sheet, "A4", "A4", styles.header); err != nil {
+		return 0, err
+	}
+	for _, filter := range filters {
+		row++
+		if err := f.SetSheetRow(sheet, "A"+strconv.Itoa(row), &[]interface{}{filter[0], filter[1]}); err != nil {
+			return 0, err
+		}
+	}
+	return row + 2, nil
+}
+
+// writeTable writes rows from the given row, the first one being a bold
+// header. columnStyles sets the style of the data cells of some columns.
+func writeTable(f *excelize.File, sheet string, row int, rows [][]interface{}, styles *styles, columnStyles map[int]int) error {
+	for i, values := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, row+i)
+		if err := f.SetSheetRow(sheet, cell, &values); err != nil {
+			return err
+		}
+	}
+
+	first, _ := excelize.CoordinatesToCellName(1, row)
+	last, _ := excelize.CoordinatesToCellName(len(rows[0]), row)
+	if err := f.SetCellStyle(sheet, first, last, styles.header); err != nil {
+		return err
+	}
+	for col, style := range columnStyles {
+		top, _ := excelize.CoordinatesToCellName(col+1, row+1)
+		bottom, _ := excelize.CoordinatesToCellName(col+1, row+len(rows)-1)
+		if err := f.SetCellStyle(sheet, top, bottom, style); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterDescription lists the filters of a report as label and value pairs.
+// Wilaya, categories and avantages are always listed, other conditions only
+// when they are set.
+func filterDescription(filter domain.PensionFilter, ref *domain.ReferenceData) [][2]string {
+	all := func(values []string) string {
+		if len(values) == 0 {
+			return "Toutes"
+		}
+		return strings.Join(values, ", ")
+	}
+	wilaya := filter.Wilaya
+	if wilaya == "" {
+		wilaya = "Toutes"
+	} else if code, err := strconv.Atoi(wilaya); err == nil {
+		if w, ok := ref.Wilaya(code); ok {
+			wilaya = fmt.Sprintf("%d - %s", w.Code, w.Name)
+		}
+	}
+	lines := [][2]string{
+		{"Wilaya", wilaya},
+		{"Catégories", all(filter.Categories)},
+		{"Avantages", all(filter.Avantages)},
+	}
+
+	if filter.Agency != "" {
+		agency := filter.Agency
+		if code, err := strconv.ParseInt(agency, 10, 8); err == nil {
+			if a, ok := ref.Agency(int8(code)); ok {
+				agency = fmt.Sprintf("%d - %s", a.Code, a.Name)
+			}
+		}
+		lines = append(lines, [2]string{"Agence", agency})
+	}
+
+	if len(filter.Sexe) > 0 {
+		lines = append(lines, [2]string{"Sexe", strings.Join(filter.Sexe, ", ")})
+	}
+	if len(filter.RiskLevels) > 0 {
+		levels := make([]string, 0, len(filter.RiskLevels))
+		for _, level := range filter.RiskLevels {
+			levels = append(levels, ref.RiskLevelLabel(level))
+		}
+		lines = append(lines, [2]string{"Niveau de risque", strings.Join(levels, ", ")})
+	}
+	for _, r := range filter.NumericRanges() {
+		if r.Range.Min != nil {
+			lines = append(lines, [2]string{r.Field, ">= " + formatFloat(*r.Range.Min)})
+		}
+		if r.Range.Max != nil {
+			lines = append(lines, [2]string{r.Field, "<= " + formatFloat(*r.Range.Max)})
+		}
+	}
+	for _, r := range filter.DateRanges() {
+		if r.Range.After != nil {
+			lines = append(lines, [2]string{r.Field, "après le " + r.Range.After.Format("02/01/2006")})
+		}
+		if r.Range.Before != nil {
+			lines = append(lines, [2]string{r.Field, "avant le " + r.Range.Before.Format("02/01/2006")})
+		}
+	}
+	return lines
+}
@@ -0,0 +1,120 @@
+package exporter
+
+import (
+	"bufio"
+	"cnr-tp/domain"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// flushEvery is the number of records written between two flushes of a
+// streamed export, so the client receives data while the export runs
+const flushEvery = 1000
+
+// csvColumns use the JSON names of PensionData, so that CSV and NDJSON
+// exports share their field names and can be imported again
+var csvColumns = []struct {
+	header string
+	value  func(p *domain.PensionData) string
+}{
+	{"id", func(p *domain.PensionData) string { return strconv.FormatUint(uint64(p.ID), 10) }},
+	{"ag", func(p *domain.PensionData) string { return strconv.Itoa(int(p.AG)) }},
+	{"avt", func(p *domain.PensionData) string { return p.AVT }},
+	{"npens", func(p *domain.PensionData) string { return p.NPens }},
+	{"etatpens", func(p *domain.PensionData) string { return p.EtatPens }},
+	{"datenais", func(p *domain.PensionData) string { return formatDate(p.DateNais) }},
+	{"datjouis", func(p *domain.PensionData) string { return formatDate(p.DateJouis) }},
+	{"sexe_tp", func(p *domain.PensionData) string { return p.SexeTP }},
+	{"net_mens", func(p *domain.PensionData) string { return formatFloat(p.NetMens) }},
+	{"taux_d", func(p *domain.PensionData) string { return formatFloat(p.TauxD) }},
+	{"taux_rv", func(p *domain.PensionData) string { return formatFloat(p.TauxRV) }},
+	{"taux_glb", func(p *domain.PensionData) string { return formatFloat(p.TauxGLB) }},
+	{"age_app_tp", func(p *domain.PensionData) string { return strconv.Itoa(int(p.AgeAppTP)) }},
+	{"duree_pension", func(p *domain.PensionData) string { return strconv.Itoa(p.DureePension) }},
+	{"age_moyen_cat", func(p *domain.PensionData) string { return strconv.Itoa(int(p.AgeMoyenCat)) }},
+	{"risque_age", func(p *domain.PensionData) string { return strconv.Itoa(int(p.RisqueAge)) }},
+	{"niveau_risque_predit", func(p *domain.PensionData) string { return strconv.Itoa(int(p.NiveauRisquePredit)) }},
+	{"wilaya", func(p *domain.PensionData) string { return p.Wilaya }},
+}
+
+// WriteCSV streams the records matching the filters to w as CSV, reading
+// them from the database in chunks
+func (e *Exporter) WriteCSV(w io.Writer, delimiter rune, filter domain.PensionFilter) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = delimiter
+
+	record := make([]string, len(csvColumns))
+	for i, col := range csvColumns {
+		record[i] = col.header
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+
+	count := 0
+	err := e.pensionUseCase.ExportPensions(filter, func(p *domain.PensionData) error {
+		for i, col := range csvColumns {
+			record[i] = col.value(p)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+		if count++; count%flushEvery == 0 {
+			cw.Flush()
+			flush(w)
+		}
+		return cw.Error()
+	})
+	cw.Flush()
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// WriteNDJSON streams the records matching the filters to w as one JSON
+// object per line, reading them from the database in chunks
+func (e *Exporter) WriteNDJSON(w io.Writer, filter domain.PensionFilter) error {
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+
+	count := 0
+	err := e.pensionUseCase.ExportPensions(filter, func(p *domain.PensionData) error {
+		if err := encoder.Encode(p); err != nil {
+			return err
+		}
+		if count++; count%flushEvery == 0 {
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			flush(w)
+		}
+		return nil
+	})
+	if flushErr := bw.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// flush pushes buffered response data to the client when w is an HTTP response
+func flush(w io.Writer) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
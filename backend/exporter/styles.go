@@ -0,0 +1,58 @@
+package exporter
+
+import (
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// styles holds the cell style IDs shared by the sheets of a workbook
+type styles struct {
+	header  int
+	date    int
+	amount  int
+	rate    int
+	percent int
+}
+
+func newStyles(f *excelize.File) (*styles, error) {
+	dateFormat := "dd/mm/yyyy"
+	amountFormat := "#,##0.00"
+	rateFormat := "0.##"
+
+	s := &styles{}
+	definitions := []struct {
+		id    *int
+		style *excelize.Style
+	}{
+		{&s.header, &excelize.Style{Font: &excelize.Font{Bold: true}}},
+		{&s.date, &excelize.Style{CustomNumFmt: &dateFormat}},
+		{&s.amount, &excelize.Style{CustomNumFmt: &amountFormat}},
+		{&s.rate, &excelize.Style{CustomNumFmt: &rateFormat}},
+		{&s.percent, &excelize.Style{NumFmt: 10}},
+	}
+	for _, d := range definitions {
+		id, err := f.NewStyle(d.style)
+		if err != nil {
+			return nil, err
+		}
+		*d.id = id
+	}
+	return s, nil
+}
+
+// cell wraps a value with the style of its column kind. Zero dates are left blank.
+func (s *styles) cell(kind columnKind, value interface{}) interface{} {
+	switch kind {
+	case kindDate:
+		if t, ok := value.(time.Time); ok && t.IsZero() {
+			return nil
+		}
+		return excelize.Cell{StyleID: s.date, Value: value}
+	case kindAmount:
+		return excelize.Cell{StyleID: s.amount, Value: value}
+	case kindRate:
+		return excelize.Cell{StyleID: s.rate, Value: value}
+	}
+	return value
+}
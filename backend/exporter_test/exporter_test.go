@@ -0,0 +1,226 @@
+package exporter_test
+
+import (
+	"bytes"
+	"cnr-tp/domain"
+	"cnr-tp/exporter"
+	"cnr-tp/importer"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+// stubPensionUseCase serves a fixed set of records to the exporter
+type stubPensionUseCase struct {
+	domain.PensionUseCase
+	pensions []domain.PensionData
+	stats    []domain.RiskLevelStats
+	wilayas  []domain.WilayaStats
+}
+
+func (s *stubPensionUseCase) ExportPensions(filter domain.PensionFilter, fn func(pension *domain.PensionData) error) error {
+	for i := range s.pensions {
+		if err := fn(&s.pensions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *stubPensionUseCase) GetRiskLevelStats(filter domain.PensionFilter) ([]domain.RiskLevelStats, error) {
+	return s.stats, nil
+}
+
+func (s *stubPensionUseCase) GetWilayaStats(filter domain.PensionFilter) ([]domain.WilayaStats, error) {
+	return s.wilayas, nil
+}
+
+func TestPensionWorkbook(t *testing.T) {
+	uc := &stubPensionUseCase{
+		pensions: []domain.PensionData{
+			{ID: 1, AG: 16, AVT: "3", NPens: "P1", DateNais: time.Date(1950, 3, 1, 0, 0, 0, 0, time.UTC), NetMens: 15000.5, NiveauRisquePredit: 2},
+			{ID: 2, AG: 31, AVT: "1", NPens: "P2", NiveauRisquePredit: 0},
+		},
+		stats: []domain.RiskLevelStats{
+			{RiskLevel: "Bas risque", Count: 1, Percentage: 50},
+			{RiskLevel: "Haut risque", Count: 1, Percentage: 50},
+		},
+	}
+	e := exporter.New(uc, nil, "")
+
+	f, err := e.PensionWorkbook(domain.PensionFilter{})
+	assert.NoError(t, err, "Failed to build workbook")
+	defer f.Close()
+
+	var buf bytes.Buffer
+	assert.NoError(t, e.Write(f, &buf))
+
+	out, err := excelize.OpenReader(&buf)
+	assert.NoError(t, err, "Failed to read exported workbook")
+	defer out.Close()
+
+	assert.Equal(t, []string{"Pensions", "Risk summary"}, out.GetSheetList())
+
+	rows, err := out.GetRows("Pensions", excelize.Options{RawCellValue: true})
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, "Avantage", rows[0][4])
+
+	// Test: labels are added next to the codes, and values keep their types
+	assert.Equal(t, "Veuves", rows[1][4])
+	assert.Equal(t, "Haut risque", rows[1][19])
+	assert.Equal(t, "Direct", rows[2][4])
+	assert.Equal(t, "18323", rows[1][7], "Dates should be stored as Excel dates")
+	assert.Equal(t, "", rows[2][7], "Zero dates should be left blank")
+	assert.Equal(t, "15000.5", rows[1][10])
+
+	summary, err := out.GetRows("Risk summary")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Total", "2"}, summary[3])
+	assert.Equal(t, "50.00%", summary[1][2])
+}
+
+func TestPensionWorkbook_TooManyRecords(t *testing.T) {
+	uc := &stubPensionUseCase{stats: []domain.RiskLevelStats{
+		{RiskLevel: "Bas risque", Count: exporter.MaxWorkbookRecords},
+		{RiskLevel: "Haut risque", Count: 1},
+	}}
+
+	// Test: records that do not fit on the sheet are refused before the export
+	f, err := exporter.New(uc, nil, "").PensionWorkbook(domain.PensionFilter{})
+	assert.ErrorIs(t, err, exporter.ErrTooManyRecords)
+	assert.ErrorContains(t, err, "export.csv")
+	assert.Nil(t, f)
+
+	// Test: a full sheet is still exported
+	uc.stats = uc.stats[:1]
+	f, err = exporter.New(uc, nil, "").PensionWorkbook(domain.PensionFilter{})
+	assert.NoError(t, err)
+	f.Close()
+}
+
+// stubReferenceUseCase serves fixed reference data
+type stubReferenceUseCase struct {
+	domain.ReferenceUseCase
+	data *domain.ReferenceData
+}
+
+func (s *stubReferenceUseCase) GetReferenceData() (*domain.ReferenceData, error) {
+	return s.data, nil
+}
+
+func TestPensionWorkbook_ReferenceLabels(t *testing.T) {
+	uc := &stubPensionUseCase{pensions: []domain.PensionData{{ID: 1, AVT: "K", NiveauRisquePredit: 2}}}
+	ref := domain.DefaultReferenceData()
+	ref.AvantageCategories = append(ref.AvantageCategories, domain.AvantageCategory{Name: "orphelins", Label: "Orphelins", Codes: []string{"K"}})
+	ref.RiskLevels[2].Label = "Risque élevé"
+	e := exporter.New(uc, &stubReferenceUseCase{data: ref}, "")
+
+	f, err := e.PensionWorkbook(domain.PensionFilter{})
+	assert.NoError(t, err, "Failed to build workbook")
+	defer f.Close()
+
+	// Test: labels follow the edited reference data
+	rows, err := f.GetRows("Pensions")
+	assert.NoError(t, err)
+	assert.Equal(t, "Orphelins", rows[1][4])
+	assert.Equal(t, "Risque élevé", rows[1][19])
+}
+
+func TestRiskReport(t *testing.T) {
+	uc := &stubPensionUseCase{
+		stats: []domain.RiskLevelStats{
+			{RiskLevel: "Bas risque", Count: 3, Percentage: 75},
+			{RiskLevel: "Haut risque", Count: 1, Percentage: 25},
+		},
+		wilayas: []domain.WilayaStats{
+			{AG: 16, Wilaya: "Alger", Count: 3, Percentage: 75},
+			{AG: 31, Count: 1, Percentage: 25},
+		},
+	}
+	e := exporter.New(uc, nil, "")
+
+	after := domain.Date{Time: time.Date(1935, 1, 1, 0, 0, 0, 0, time.UTC)}
+	f, err := e.RiskReport(domain.PensionFilter{
+		Wilaya:     "16",
+		Categories: []string{"Veuves"},
+		RiskLevels: []int8{2},
+		DateNais:   domain.DateRange{After: &after},
+	})
+	assert.NoError(t, err, "Failed to build report")
+	defer f.Close()
+
+	var buf bytes.Buffer
+	assert.NoError(t, e.Write(f, &buf))
+
+	out, err := excelize.OpenReader(&buf)
+	assert.NoError(t, err, "Failed to read report")
+	defer out.Close()
+
+	assert.Equal(t, []string{"Summary", "Wilayas"}, out.GetSheetList())
+
+	// Test: the filters are described above the tables
+	summary, err := out.GetRows("Summary")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Wilaya", "16 - Alger"}, summary[4])
+	assert.Equal(t, []string{"Catégories", "Veuves"}, summary[5])
+	assert.Equal(t, []string{"Avantages", "Toutes"}, summary[6])
+	assert.Equal(t, []string{"Niveau de risque", "Haut risque"}, summary[7])
+	assert.Equal(t, []string{"datenais", "après le 01/01/1935"}, summary[8])
+	assert.Equal(t, []string{"Niveau de risque", "Nombre", "Pourcentage"}, summary[10])
+	assert.Equal(t, []string{"Bas risque", "3", "75.00%"}, summary[11])
+	assert.Equal(t, []string{"Total", "4"}, summary[13])
+
+	// Test: wilayas without a name are labelled by their AG
+	wilayas, err := out.GetRows("Wilayas")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"31", "AG 31", "1", "25.00%"}, wilayas[12])
+
+	// Test: the pie and bar charts are stored as native charts
+	for _, part := range []string{"xl/charts/chart1.xml", "xl/charts/chart2.xml"} {
+		_, ok := out.Pkg.Load(part)
+		assert.True(t, ok, "Missing %s", part)
+	}
+}
+
+func TestWriteCSV_RoundTrip(t *testing.T) {
+	uc := &stubPensionUseCase{pensions: []domain.PensionData{{
+		ID: 1, AG: 16, AVT: "3", NPens: "P1", EtatPens: "A", SexeTP: "F",
+		DateNais:  time.Date(1950, 3, 1, 0, 0, 0, 0, time.UTC),
+		DateJouis: time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC),
+		NetMens:   15000.5, TauxD: 80, TauxGLB: 80, AgeAppTP: 60, DureePension: 14, NiveauRisquePredit: 2,
+	}}}
+
+	path := filepath.Join(t.TempDir(), "pensions.csv")
+	out, err := os.Create(path)
+	assert.NoError(t, err)
+	assert.NoError(t, exporter.New(uc, nil, "").WriteCSV(out, ';', domain.PensionFilter{}))
+	assert.NoError(t, out.Close())
+
+	// Test: the export goes through the importer column mapping unchanged
+	mapping, err := importer.LoadMapping("../config/column_mapping.json")
+	assert.NoError(t, err)
+	report, err := importer.New(nil, nil, nil, mapping, domain.ImportOptions{}).Validate(path, domain.ImportOptions{Delimiter: ";"})
+	assert.NoError(t, err, "Failed to validate exported CSV")
+	assert.Equal(t, 1, report.ValidRows)
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	uc := &stubPensionUseCase{pensions: []domain.PensionData{{ID: 1, NPens: "P1"}, {ID: 2, NPens: "P2"}}}
+
+	var buf bytes.Buffer
+	assert.NoError(t, exporter.New(uc, nil, "").WriteNDJSON(&buf, domain.PensionFilter{}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var p domain.PensionData
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &p))
+	assert.Equal(t, "P2", p.NPens)
+}
@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// builtinDateLayouts are tried after the configured layouts. Day-first
+// layouts follow the regional format of the agencies (02/01/2006 is 2 January).
+var builtinDateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC3339,
+	"2/1/2006",
+	"2/1/2006 15:04:05",
+	"2/1/2006 15:04",
+	"2-1-2006",
+	"2.1.2006",
+}
+
+// Excel serial dates accepted, from 1900-01-01 to 9999-12-31
+const (
+	minExcelSerial = 1
+	maxExcelSerial = 2958465
+)
+
+// DateParser normalises the date formats found in pension files: ISO and
+// day-first dates, any configured layouts and, in workbooks, Excel serial
+// numbers. A nil DateParser accepts the built-in layouts only.
+type DateParser struct {
+	layouts []string
+	// serials accepts Excel serial numbers, counted from 1904-01-01 rather
+	// than 1900-01-01 when date1904 is set
+	serials  bool
+	date1904 bool
+}
+
+// NewDateParser returns a parser of delimited and NDJSON files, trying
+// layouts, in the Go time layout syntax, before the built-in ones. Numbers are
+// not dates there: a year such as 1935 would read as a serial.
+func NewDateParser(layouts []string) *DateParser {
+	return &DateParser{layouts: layouts}
+}
+
+// NewWorkbookDateParser returns a parser of workbook cells, which also
+// accepts Excel serial numbers in the date system of the workbook
+func NewWorkbookDateParser(layouts []string, date1904 bool) *DateParser {
+	return &DateParser{layouts: layouts, serials: true, date1904: date1904}
+}
+
+// workbookSource is implemented by the sources whose numeric cells hold
+// Excel serial dates
+type workbookSource interface {
+	// Date1904 reports whether the workbook uses the 1904 date system
+	Date1904() bool
+}
+
+// dateParserFor returns the DateParser of the rows of source
+func dateParserFor(source Source, layouts []string) *DateParser {
+	if wb, ok := source.(workbookSource); ok {
+		return NewWorkbookDateParser(layouts, wb.Date1904())
+	}
+	return NewDateParser(layouts)
+}
+
+func (d *DateParser) Parse(v string) (time.Time, error) {
+	var layouts []string
+	if d != nil {
+		layouts = d.layouts
+	}
+
+	for _, list := range [][]string{layouts, builtinDateLayouts} {
+		for _, layout := range list {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+	}
+
+	if serial, err := strconv.ParseFloat(v, 64); err == nil && d != nil && d.serials {
+		if serial < minExcelSerial || serial > maxExcelSerial {
+			return time.Time{}, fmt.Errorf("excel serial date %s out of range", v)
+		}
+		return excelize.ExcelDateToTime(serial, d.date1904)
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognised date %q", v)
+}
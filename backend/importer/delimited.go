@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// delimitedReader reads CSV and TSV files
+type delimitedReader struct {
+	file   *os.File
+	csv    *csv.Reader
+	header []string
+}
+
+var encodings = map[string]encoding.Encoding{
+	"windows-1252": charmap.Windows1252,
+	"cp1252":       charmap.Windows1252,
+	"iso-8859-1":   charmap.ISO8859_1,
+	"latin1":       charmap.ISO8859_1,
+	"iso-8859-15":  charmap.ISO8859_15,
+	"utf-16":       unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+}
+
+func openDelimited(filePath, delimiter, encodingName string) (RowReader, error) {
+	comma, size := utf8.DecodeRuneInString(delimiter)
+	if size == 0 || size != len(delimiter) {
+		return nil, fmt.Errorf("delimiter must be a single character, got %q", delimiter)
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+
+	var src io.Reader = f
+	switch name := strings.ToLower(encodingName); name {
+	case "", "utf-8", "utf8":
+		// Drop the byte order mark some spreadsheet tools write
+		br := bufio.NewReader(f)
+		if r, _, err := br.ReadRune(); err != nil || r != '\uFEFF' {
+			br.UnreadRune()
+		}
+		src = br
+	default:
+		enc, ok := encodings[name]
+		if !ok {
+			f.Close()
+			return nil, fmt.Errorf("unsupported encoding %q", encodingName)
+		}
+		src = enc.NewDecoder().Reader(f)
+	}
+
+	r := csv.NewReader(src)
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	header, err := r.Read()
+	if err != nil {
+		f.Close()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("file is empty")
+		}
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+
+	return &delimitedReader{file: f, csv: r, header: header}, nil
+}
+
+func (r *delimitedReader) Header() []string {
+	return r.header
+}
+
+func (r *delimitedReader) Next() ([]string, error) {
+	row, err := r.csv.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, &RowError{Err: parseErr.Err}
+	}
+	return row, err
+}
+
+func (r *delimitedReader) Close() error {
+	return r.file.Close()
+}
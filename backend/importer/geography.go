@@ -0,0 +1,37 @@
+package importer
+
+import (
+	"cnr-tp/domain"
+	"fmt"
+)
+
+// locateAgency checks the parsed AG code against the known agencies and sets
+// Wilaya to the name of the wilaya of the agency. Rows of files without an AG
+// column are left alone.
+func locateAgency(p *domain.PensionData, index HeaderIndex, ref *domain.ReferenceData) *FieldError {
+	if _, ok := index["AG"]; !ok {
+		return nil
+	}
+
+	agency, ok := ref.Agency(p.AG)
+	if !ok {
+		return &FieldError{Field: "AG", Err: fmt.Errorf("unknown agency code %d", p.AG)}
+	}
+	if wilaya, ok := ref.Wilaya(agency.WilayaCode); ok {
+		p.Wilaya = wilaya.Name
+	}
+	return nil
+}
+
+// referenceData returns the geography rows are checked against, the default
+// one when the importer has no reference use case
+func (im *Importer) referenceData() (*domain.ReferenceData, error) {
+	if im.referenceUseCase == nil {
+		return domain.DefaultReferenceData(), nil
+	}
+	ref, err := im.referenceUseCase.GetReferenceData()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load geography: %v", err)
+	}
+	return ref, nil
+}
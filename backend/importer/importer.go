@@ -0,0 +1,442 @@
+package importer
+
+import (
+	"cnr-tp/domain"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Importer loads pension files into the database
+type Importer struct {
+	pensionUseCase domain.PensionUseCase
+	// referenceUseCase provides the agencies AG codes are checked against; the
+	// default geography is used when nil
+	referenceUseCase domain.ReferenceUseCase
+	// batches records each import run; nil when the importer only validates files
+	batches  domain.ImportBatchRepository
+	mapping  *ColumnMapping
+	defaults domain.ImportOptions
+}
+
+func New(pensionUseCase domain.PensionUseCase, referenceUseCase domain.ReferenceUseCase, batches domain.ImportBatchRepository, mapping *ColumnMapping, defaults domain.ImportOptions) *Importer {
+	return &Importer{pensionUseCase: pensionUseCase, referenceUseCase: referenceUseCase, batches: batches, mapping: mapping, defaults: defaults}
+}
+
+// ImportFile streams the file at filePath through the reader matching its
+// format and loads every valid row in batches. Workbooks are imported sheet
+// by sheet. Progress is logged, and passed to progress when not nil, every
+// ProgressEvery rows. The run is recorded as an import batch referenced by
+// every record it creates.
+func (im *Importer) ImportFile(filePath string, opts domain.ImportOptions, progress func(domain.ImportSummary)) (domain.ImportSummary, error) {
+	opts = im.withDefaults(opts)
+
+	log.Printf("Opening import file: %s", filePath)
+
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return domain.ImportSummary{}, fmt.Errorf("failed to get file info: %v", err)
+	}
+	log.Printf("File size: %d bytes", fileInfo.Size())
+
+	batch, err := im.startBatch(filePath, opts)
+	if err != nil {
+		return domain.ImportSummary{}, err
+	}
+
+	var batchID *uint
+	if batch != nil {
+		batchID = &batch.ID
+	}
+	summary, err := im.importFile(filePath, opts, batchID, progress)
+	im.finishBatch(batch, summary, err)
+	return summary, err
+}
+
+// importFile loads every selected sheet of filePath
+func (im *Importer) importFile(filePath string, opts domain.ImportOptions, batchID *uint, progress func(domain.ImportSummary)) (domain.ImportSummary, error) {
+	var summary domain.ImportSummary
+	if batchID != nil {
+		summary.BatchID = *batchID
+	}
+
+	source, err := OpenSource(filePath, opts)
+	if err != nil {
+		return summary, err
+	}
+	defer func() {
+		if err := source.Close(); err != nil {
+			log.Printf("error closing import file: %v", err)
+		}
+	}()
+
+	sheets, err := selectSheets(source, opts.Sheets)
+	if err != nil {
+		return summary, err
+	}
+
+	ref, err := im.referenceData()
+	if err != nil {
+		return summary, err
+	}
+
+	rejects := newRejectReport(opts.RejectReportPath, opts.RejectReportPassword)
+	defer func() {
+		if err := rejects.Close(); err != nil {
+			log.Printf("error writing rejected rows report: %v", err)
+		}
+	}()
+
+	load := func(pensionUseCase domain.PensionUseCase) error {
+		for _, sheet := range sheets {
+			sheetSummary, err := im.importSheet(pensionUseCase, source, sheet, opts, ref, batchID, rejects, func(s domain.ImportSummary) {
+				if progress != nil {
+					progress(addSummary(summary, s))
+				}
+			})
+			summary = addSummary(summary, sheetSummary)
+
+			// Delimited and NDJSON files have a single unnamed sheet
+			if sheet == "" {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			entry := domain.SheetSummary{Sheet: sheet, ImportSummary: sheetSummary}
+			if err != nil {
+				if opts.Mode == domain.ImportModeAtomic {
+					return fmt.Errorf("sheet %s: %v", sheet, err)
+				}
+				log.Printf("Sheet %s: %v", sheet, err)
+				entry.Error = err.Error()
+			}
+			summary.Sheets = append(summary.Sheets, entry)
+		}
+
+		if summary.RowsProcessed == 0 {
+			return errors.New("file has no data rows (headers only or empty)")
+		}
+
+		if opts.Mode == domain.ImportModeAtomic {
+			rate := float64(summary.RowsRejected) / float64(summary.RowsProcessed)
+			if rate > *opts.MaxRejectRate {
+				return fmt.Errorf("rejection rate %.2f%% exceeds threshold %.2f%%", rate*100, *opts.MaxRejectRate*100)
+			}
+		}
+		return nil
+	}
+
+	if opts.Mode == domain.ImportModeAtomic {
+		if err := im.pensionUseCase.Transaction(load); err != nil {
+			summary.RowsInserted, summary.RowsUpdated, summary.RowsUnchanged = 0, 0, 0
+			// Copy before resetting: earlier progress snapshots share the slice
+			sheets := make([]domain.SheetSummary, len(summary.Sheets))
+			for i, s := range summary.Sheets {
+				s.RowsInserted, s.RowsUpdated, s.RowsUnchanged = 0, 0, 0
+				sheets[i] = s
+			}
+			summary.Sheets = sheets
+			return summary, fmt.Errorf("import rolled back: %v", err)
+		}
+	} else if err := load(im.pensionUseCase); err != nil {
+		return summary, err
+	}
+
+	log.Printf("Import finished: %d rows inserted, %d updated, %d unchanged, %d errors",
+		summary.RowsInserted, summary.RowsUpdated, summary.RowsUnchanged, summary.RowsRejected)
+	return summary, nil
+}
+
+// importSheet loads one sheet and returns its own counters. progress receives
+// the sheet counters every ProgressEvery rows.
+func (im *Importer) importSheet(pensionUseCase domain.PensionUseCase, source Source, sheet string, opts domain.ImportOptions, ref *domain.ReferenceData, batchID *uint, rejects *rejectReport, progress func(domain.ImportSummary)) (domain.ImportSummary, error) {
+	var summary domain.ImportSummary
+
+	reader, err := im.openSheet(source, sheet, opts, ref)
+	if err != nil {
+		return summary, err
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("error closing sheet %s: %v", sheet, err)
+		}
+	}()
+
+	header := reader.Header()
+	index, err := im.mapping.Resolve(header)
+	if err != nil {
+		return summary, err
+	}
+	rejects.Sheet(sheet, header)
+
+	reject := func(rowNum int, row []string, err error) {
+		log.Printf("Row %d: %v", rowNum, err)
+		summary.RowsRejected++
+
+		column := ""
+		var fieldErr *FieldError
+		if errors.As(err, &fieldErr) {
+			column = header[index[fieldErr.Field]]
+		}
+		if err := rejects.Add(rowNum, column, err.Error(), row); err != nil {
+			log.Printf("Row %d: failed to add to rejected rows report: %v", rowNum, err)
+		}
+	}
+
+	done := make(chan struct{})
+	rows, errc := parseRows(reader, index, dateParserFor(source, opts.DateLayouts), ref, opts.BatchSize, done)
+	// Stop the row reader if loading ends early, and wait for it to exit
+	// before the sheet is closed
+	defer func() {
+		close(done)
+		for range rows {
+		}
+	}()
+
+	batch := make([]domain.PensionData, 0, opts.BatchSize)
+	// Source rows and row numbers of the batch, kept for the rejected rows report
+	var batchRows [][]string
+	var batchRowNums []int
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := im.write(pensionUseCase, batch, opts, &summary); err != nil {
+			if opts.Mode == domain.ImportModeAtomic {
+				return fmt.Errorf("batch write failed: %v", err)
+			}
+			if len(batch) == 1 {
+				reject(batchRowNums[0], batchRows[0], fmt.Errorf("write error: %v", err))
+			} else {
+				// Retry the rows one at a time so that only the failing ones
+				// are rejected, such as a key already stored
+				log.Printf("Batch write error (%d rows), retrying row by row: %v", len(batch), err)
+				for i, row := range batchRows {
+					if err := im.write(pensionUseCase, batch[i:i+1], opts, &summary); err != nil {
+						reject(batchRowNums[i], row, fmt.Errorf("write error: %v", err))
+					}
+				}
+			}
+		}
+		batch, batchRows, batchRowNums = batch[:0], batchRows[:0], batchRowNums[:0]
+		return nil
+	}
+
+	for row := range rows {
+		summary.RowsProcessed++
+
+		if row.err != nil {
+			reject(row.num, row.raw, row.err)
+		} else {
+			data := *row.data
+			data.ImportBatchID, data.SourceSheet, data.SourceRow = batchID, sheet, row.num
+			batch = append(batch, data)
+			batchRows = append(batchRows, row.raw)
+			batchRowNums = append(batchRowNums, row.num)
+			if len(batch) >= opts.BatchSize {
+				if err := flush(); err != nil {
+					return summary, err
+				}
+			}
+		}
+
+		if summary.RowsProcessed%opts.ProgressEvery == 0 {
+			log.Printf("Import progress: %d rows processed, %d rejected", summary.RowsProcessed, summary.RowsRejected)
+			progress(summary)
+		}
+	}
+	select {
+	case err := <-errc:
+		return summary, err
+	default:
+	}
+
+	return summary, flush()
+}
+
+// openSheet opens a sheet of source. With SheetAsWilaya, AG and Wilaya
+// columns missing from the sheet are derived from its name, which is a code
+// or a name of the geography.
+func (im *Importer) openSheet(source Source, sheet string, opts domain.ImportOptions, ref *domain.ReferenceData) (RowReader, error) {
+	reader, err := source.OpenSheet(sheet)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.SheetAsWilaya || sheet == "" {
+		return reader, nil
+	}
+
+	var names, values []string
+	ag, wilaya := sheetGeography(sheet, ref)
+	if ag != "" && !im.mapping.Has(reader.Header(), "AG") {
+		names, values = append(names, "AG"), append(values, ag)
+	}
+	if wilaya != "" && !im.mapping.Has(reader.Header(), "Wilaya") {
+		names, values = append(names, "Wilaya"), append(values, wilaya)
+	}
+	return withConstants(reader, names, values), nil
+}
+
+// startBatch records the start of an import run. It returns nil when the
+// importer has no batch repository.
+func (im *Importer) startBatch(filePath string, opts domain.ImportOptions) (*domain.ImportBatch, error) {
+	if im.batches == nil {
+		return nil, nil
+	}
+
+	checksum, err := fileChecksum(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &domain.ImportBatch{
+		FileName:  filepath.Base(filePath),
+		Checksum:  checksum,
+		UserID:    opts.UserID,
+		Status:    domain.ImportStatusRunning,
+		StartedAt: time.Now(),
+	}
+	if opts.FileName != "" {
+		batch.FileName = opts.FileName
+	}
+	if err := im.batches.Create(batch); err != nil {
+		return nil, fmt.Errorf("failed to record import batch: %v", err)
+	}
+	return batch, nil
+}
+
+// finishBatch stores the outcome of an import run on its batch
+func (im *Importer) finishBatch(batch *domain.ImportBatch, summary domain.ImportSummary, err error) {
+	if batch == nil {
+		return
+	}
+
+	now := time.Now()
+	batch.FinishedAt = &now
+	batch.RowsProcessed = summary.RowsProcessed
+	batch.RowsInserted = summary.RowsInserted
+	batch.RowsUpdated = summary.RowsUpdated
+	batch.RowsUnchanged = summary.RowsUnchanged
+	batch.RowsRejected = summary.RowsRejected
+	batch.Status = domain.ImportStatusCompleted
+	if err != nil {
+		batch.Status = domain.ImportStatusFailed
+		batch.Error = err.Error()
+	}
+
+	if err := im.batches.Update(batch); err != nil {
+		log.Printf("error updating import batch %d: %v", batch.ID, err)
+	}
+}
+
+// write stores one batch, either as plain inserts or as a natural-key upsert
+func (im *Importer) write(pensionUseCase domain.PensionUseCase, batch []domain.PensionData, opts domain.ImportOptions, summary *domain.ImportSummary) error {
+	if !*opts.Upsert {
+		if err := pensionUseCase.CreatePensions(batch, opts.BatchSize); err != nil {
+			return err
+		}
+		summary.RowsInserted += len(batch)
+		return nil
+	}
+
+	result, err := pensionUseCase.UpsertPensions(batch, opts.UpsertKey, opts.BatchSize)
+	if err != nil {
+		return err
+	}
+	summary.RowsInserted += result.Inserted
+	summary.RowsUpdated += result.Updated
+	summary.RowsUnchanged += result.Unchanged
+	return nil
+}
+
+// addSummary returns the sum of the row counters of a and b, keeping the
+// sheet list of a
+func addSummary(a, b domain.ImportSummary) domain.ImportSummary {
+	a.RowsProcessed += b.RowsProcessed
+	a.RowsInserted += b.RowsInserted
+	a.RowsUpdated += b.RowsUpdated
+	a.RowsUnchanged += b.RowsUnchanged
+	a.RowsRejected += b.RowsRejected
+	return a
+}
+
+// withDefaults fills unset options from the importer defaults
+func (im *Importer) withDefaults(opts domain.ImportOptions) domain.ImportOptions {
+	if opts.Delimiter == "" {
+		opts.Delimiter = im.defaults.Delimiter
+	}
+	if opts.Encoding == "" {
+		opts.Encoding = im.defaults.Encoding
+	}
+	if opts.Mode == "" {
+		opts.Mode = im.defaults.Mode
+	}
+	if opts.Mode == "" {
+		opts.Mode = domain.ImportModeBestEffort
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = im.defaults.BatchSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.MaxRejectRate == nil {
+		opts.MaxRejectRate = im.defaults.MaxRejectRate
+	}
+	if opts.MaxRejectRate == nil {
+		rate := 0.0
+		opts.MaxRejectRate = &rate
+	}
+	if opts.Upsert == nil {
+		opts.Upsert = im.defaults.Upsert
+	}
+	if opts.Upsert == nil {
+		upsert := false
+		opts.Upsert = &upsert
+	}
+	if opts.UpsertKey == "" {
+		opts.UpsertKey = im.defaults.UpsertKey
+	}
+	if opts.UpsertKey == "" {
+		opts.UpsertKey = domain.UpsertKeyNPens
+	}
+	if opts.ProgressEvery <= 0 {
+		opts.ProgressEvery = im.defaults.ProgressEvery
+	}
+	// Passwords of the request are tried before the configured ones
+	opts.Passwords = append(append([]string(nil), opts.Passwords...), im.defaults.Passwords...)
+	if opts.RejectReportPassword == "" {
+		opts.RejectReportPassword = im.defaults.RejectReportPassword
+	}
+	if len(opts.DateLayouts) == 0 {
+		opts.DateLayouts = im.defaults.DateLayouts
+	}
+	if opts.ProgressEvery <= 0 {
+		opts.ProgressEvery = 10000
+	}
+	return opts
+}
+
+// fileChecksum returns the hex SHA-256 of the file at path
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash file: %v", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
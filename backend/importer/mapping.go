@@ -0,0 +1,115 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ColumnSpec lists the header aliases accepted for one PensionData field
+type ColumnSpec struct {
+	Field    string   `json:"field"`
+	Aliases  []string `json:"aliases"`
+	Required bool     `json:"required"`
+}
+
+// ColumnMapping describes how worksheet headers map to PensionData fields
+type ColumnMapping struct {
+	Columns []ColumnSpec `json:"columns"`
+}
+
+// HeaderIndex gives the column position of each mapped field in a sheet
+type HeaderIndex map[string]int
+
+// LoadMapping reads a column mapping from a JSON file
+func LoadMapping(path string) (*ColumnMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read column mapping %s: %v", path, err)
+	}
+
+	var mapping ColumnMapping
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse column mapping %s: %v", path, err)
+	}
+
+	for _, col := range mapping.Columns {
+		if !knownField(col.Field) {
+			return nil, fmt.Errorf("column mapping %s: unknown field %q", path, col.Field)
+		}
+	}
+
+	return &mapping, nil
+}
+
+// Resolve matches a header row against the mapping. Unknown headers are
+// ignored; a missing required column is reported as an error.
+func (m *ColumnMapping) Resolve(header []string) (HeaderIndex, error) {
+	positions := headerPositions(header)
+
+	index := make(HeaderIndex, len(m.Columns))
+	var missing []string
+	for _, col := range m.Columns {
+		if pos, ok := col.find(positions); ok {
+			index[col.Field] = pos
+		} else if col.Required {
+			missing = append(missing, col.Field)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required column(s): %s", strings.Join(missing, ", "))
+	}
+
+	return index, nil
+}
+
+// Has reports whether header contains a column mapped to field
+func (m *ColumnMapping) Has(header []string, field string) bool {
+	positions := headerPositions(header)
+	for _, col := range m.Columns {
+		if col.Field == field {
+			_, ok := col.find(positions)
+			return ok
+		}
+	}
+	return false
+}
+
+// find returns the position of the first header matching the field or one of its aliases
+func (c ColumnSpec) find(positions map[string]int) (int, bool) {
+	for _, alias := range append([]string{c.Field}, c.Aliases...) {
+		if pos, ok := positions[normalizeHeader(alias)]; ok {
+			return pos, true
+		}
+	}
+	return 0, false
+}
+
+// headerPositions maps each normalised header to its first position
+func headerPositions(header []string) map[string]int {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		key := normalizeHeader(name)
+		if _, seen := positions[key]; !seen && key != "" {
+			positions[key] = i
+		}
+	}
+	return positions
+}
+
+// normalizeHeader lower-cases a header and drops accents, spaces and
+// punctuation so that "Net Mensuel", "net_mensuel" and "NET MENSUEL" match.
+func normalizeHeader(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,157 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+const maxNDJSONLine = 1 << 20
+
+// ndjsonReader reads one JSON object per line. The keys of the first object,
+// in their original order, form the header; keys that only appear in later
+// lines are ignored.
+type ndjsonReader struct {
+	file    *os.File
+	scanner *bufio.Scanner
+	header  []string
+	first   []string
+}
+
+func openNDJSON(filePath string) (RowReader, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+
+	r := &ndjsonReader{file: f, scanner: scanner}
+
+	line, err := r.nextLine()
+	if err != nil {
+		f.Close()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("file is empty")
+		}
+		return nil, err
+	}
+
+	header, values, err := decodeOrderedObject(line)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read first record: %v", err)
+	}
+	r.header = header
+	r.first = r.toRow(values)
+
+	return r, nil
+}
+
+func (r *ndjsonReader) Header() []string {
+	return r.header
+}
+
+func (r *ndjsonReader) Next() ([]string, error) {
+	if r.first != nil {
+		row := r.first
+		r.first = nil
+		return row, nil
+	}
+
+	line, err := r.nextLine()
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var values map[string]any
+	if err := dec.Decode(&values); err != nil {
+		return nil, &RowError{Err: fmt.Errorf("invalid JSON: %v", err)}
+	}
+	return r.toRow(values), nil
+}
+
+func (r *ndjsonReader) Close() error {
+	return r.file.Close()
+}
+
+// nextLine returns the next non-blank line
+func (r *ndjsonReader) nextLine() ([]byte, error) {
+	for r.scanner.Scan() {
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) > 0 {
+			return line, nil
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (r *ndjsonReader) toRow(values map[string]any) []string {
+	row := make([]string, len(r.header))
+	for i, key := range r.header {
+		row[i] = jsonCell(values[key])
+	}
+	return row
+}
+
+// decodeOrderedObject decodes a JSON object keeping the order of its keys
+func decodeOrderedObject(line []byte) ([]string, map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, nil, errors.New("expected a JSON object")
+	}
+
+	var keys []string
+	values := make(map[string]any)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := tok.(string)
+
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, nil, err
+		}
+		if _, seen := values[key]; !seen {
+			keys = append(keys, key)
+		}
+		values[key] = v
+	}
+
+	return keys, values, nil
+}
+
+func jsonCell(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		b, _ := json.Marshal(val)
+		return string(b)
+	}
+}
@@ -0,0 +1,61 @@
+package importer
+
+import (
+	"cnr-tp/domain"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// parsedRow is one source row after parsing. Either data or err is set.
+type parsedRow struct {
+	num  int
+	raw  []string
+	data *domain.PensionData
+	err  error
+}
+
+// parseRows reads and parses rows in a separate goroutine and hands them over
+// on a channel holding at most buffer rows, so a slow database never lets the
+// reader run ahead and fill memory. The producer stops when done is closed.
+// A fatal read error is delivered on the error channel once rows is closed.
+// AG codes are checked against the agencies of ref.
+func parseRows(reader RowReader, index HeaderIndex, dates *DateParser, ref *domain.ReferenceData, buffer int, done <-chan struct{}) (<-chan parsedRow, <-chan error) {
+	rows := make(chan parsedRow, buffer)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(rows)
+
+		// Row numbers follow the source file, the header being row 1
+		for rowNum := 2; ; rowNum++ {
+			raw, err := reader.Next()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			var rowErr *RowError
+			if err != nil && !errors.As(err, &rowErr) {
+				errc <- fmt.Errorf("failed to read row %d: %v", rowNum, err)
+				return
+			}
+
+			row := parsedRow{num: rowNum, raw: raw, err: err}
+			if err == nil {
+				row.data, row.err = ParseRow(raw, index, dates)
+			}
+			if row.err == nil {
+				if err := locateAgency(row.data, index, ref); err != nil {
+					row.data, row.err = nil, err
+				}
+			}
+
+			select {
+			case rows <- row:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return rows, errc
+}
@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// defaultRejectSheet names the report sheet of single-sheet sources
+const defaultRejectSheet = "Rejected rows"
+
+// RejectReportSuffix ends the file name of every rejected rows workbook
+const RejectReportSuffix = "_rejected.xlsx"
+
+// rejectReport streams rejected rows into an error workbook, with one sheet
+// per source sheet. The file is only created once the first row is rejected.
+// Diagnostic columns come first, followed by the original columns, so each
+// sheet can be corrected and re-imported as is. The workbook is encrypted
+// when a password is set.
+type rejectReport struct {
+	path     string
+	password string
+
+	// Source sheet currently being imported
+	sheet  string
+	header []string
+
+	file *excelize.File
+	sw   *excelize.StreamWriter
+	next int
+}
+
+func newRejectReport(path, password string) *rejectReport {
+	return &rejectReport{path: path, password: password}
+}
+
+// Sheet switches the report to a new source sheet
+func (r *rejectReport) Sheet(name string, header []string) {
+	if r.sw != nil {
+		if err := r.sw.Flush(); err != nil {
+			log.Printf("error flushing rejected rows sheet: %v", err)
+		}
+		r.sw = nil
+	}
+	r.sheet, r.header = name, header
+}
+
+// Add records one rejected row. It is a no-op when no report path is set.
+func (r *rejectReport) Add(rowNum int, column, reason string, row []string) error {
+	if r.path == "" {
+		return nil
+	}
+	if r.sw == nil {
+		if err := r.openSheet(); err != nil {
+			return err
+		}
+	}
+
+	values := make([]interface{}, 0, 3+len(r.header))
+	values = append(values, rowNum, column, reason)
+	for i := range r.header {
+		if i < len(row) {
+			values = append(values, row[i])
+		} else {
+			values = append(values, "")
+		}
+	}
+
+	cell, _ := excelize.CoordinatesToCellName(1, r.next)
+	r.next++
+	return r.sw.SetRow(cell, values)
+}
+
+// Close writes the workbook to disk if any row was rejected
+func (r *rejectReport) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	defer r.file.Close()
+
+	if r.sw != nil {
+		if err := r.sw.Flush(); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return r.file.SaveAs(r.path, excelize.Options{Password: r.password})
+}
+
+// openSheet starts the report sheet of the current source sheet
+func (r *rejectReport) openSheet() error {
+	name := r.sheet
+	if name == "" {
+		name = defaultRejectSheet
+	}
+
+	if r.file == nil {
+		f := excelize.NewFile()
+		if err := f.SetSheetName(f.GetSheetName(0), name); err != nil {
+			f.Close()
+			return err
+		}
+		r.file = f
+	} else if _, err := r.file.NewSheet(name); err != nil {
+		return err
+	}
+
+	sw, err := r.file.NewStreamWriter(name)
+	if err != nil {
+		return err
+	}
+
+	bold, err := r.file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	if err := sw.SetColWidth(3, 3, 50); err != nil {
+		return err
+	}
+
+	headers := []interface{}{
+		excelize.Cell{StyleID: bold, Value: "Row Number"},
+		excelize.Cell{StyleID: bold, Value: "Failing Column"},
+		excelize.Cell{StyleID: bold, Value: "Reason"},
+	}
+	for _, h := range r.header {
+		headers = append(headers, excelize.Cell{StyleID: bold, Value: h})
+	}
+	if err := sw.SetRow("A1", headers); err != nil {
+		return fmt.Errorf("failed to write report header: %v", err)
+	}
+
+	r.sw, r.next = sw, 2
+	return nil
+}
+
+// PruneRejectReports deletes the rejected rows workbooks of dir last written
+// before cutoff, and returns how many were deleted. A missing dir holds none.
+func PruneRejectReports(dir string, cutoff time.Time) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasSuffix(entry.Name(), RejectReportSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,179 @@
+package importer
+
+import (
+	"cnr-tp/domain"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError reports the field whose value failed to parse
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("invalid %s: %v", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldParsers assigns a raw cell value to the matching PensionData field
+var fieldParsers = map[string]func(p *domain.PensionData, v string) error{
+	"AG": func(p *domain.PensionData, v string) error {
+		n, err := strconv.ParseInt(v, 10, 8)
+		p.AG = int8(n)
+		return err
+	},
+	"AVT": func(p *domain.PensionData, v string) error {
+		p.AVT = v
+		return nil
+	},
+	"NPens": func(p *domain.PensionData, v string) error {
+		p.NPens = v
+		return nil
+	},
+	"EtatPens": func(p *domain.PensionData, v string) error {
+		p.EtatPens = v
+		return nil
+	},
+	"SexeTP": func(p *domain.PensionData, v string) error {
+		p.SexeTP = v
+		return nil
+	},
+	"NetMens": func(p *domain.PensionData, v string) (err error) {
+		p.NetMens, err = strconv.ParseFloat(v, 64)
+		return err
+	},
+	"TauxD": func(p *domain.PensionData, v string) (err error) {
+		p.TauxD, err = strconv.ParseFloat(v, 64)
+		return err
+	},
+	"TauxRV": func(p *domain.PensionData, v string) (err error) {
+		p.TauxRV, err = strconv.ParseFloat(v, 64)
+		return err
+	},
+	"TauxGLB": func(p *domain.PensionData, v string) (err error) {
+		p.TauxGLB, err = strconv.ParseFloat(v, 64)
+		return err
+	},
+	"AgeAppTP": func(p *domain.PensionData, v string) error {
+		n, err := strconv.ParseInt(v, 10, 8)
+		p.AgeAppTP = int8(n)
+		return err
+	},
+	"DureePension": func(p *domain.PensionData, v string) (err error) {
+		p.DureePension, err = strconv.Atoi(v)
+		return err
+	},
+	"AgeMoyenCat": func(p *domain.PensionData, v string) error {
+		n, err := strconv.ParseInt(v, 10, 8)
+		p.AgeMoyenCat = int8(n)
+		return err
+	},
+	"RisqueAge": func(p *domain.PensionData, v string) error {
+		n, err := strconv.ParseInt(v, 10, 8)
+		p.RisqueAge = int8(n)
+		return err
+	},
+	"NiveauRisquePredit": func(p *domain.PensionData, v string) error {
+		n, err := strconv.ParseInt(v, 10, 8)
+		p.NiveauRisquePredit = int8(n)
+		return err
+	},
+	"Wilaya": func(p *domain.PensionData, v string) error {
+		p.Wilaya = v
+		return nil
+	},
+}
+
+// dateFields assigns the dates read by the DateParser of the import run
+var dateFields = map[string]func(p *domain.PensionData, t time.Time){
+	"DateNais":  func(p *domain.PensionData, t time.Time) { p.DateNais = t },
+	"DateJouis": func(p *domain.PensionData, t time.Time) { p.DateJouis = t },
+}
+
+// knownField reports whether field is a PensionData field the importer can fill
+func knownField(field string) bool {
+	_, parsed := fieldParsers[field]
+	_, date := dateFields[field]
+	return parsed || date
+}
+
+// fieldOrder is the order in which fields are parsed, so that the first
+// reported error follows the column order of the original template
+var fieldOrder = []string{
+	"AG", "AVT", "NPens", "EtatPens", "DateNais", "DateJouis", "SexeTP",
+	"NetMens", "TauxD", "TauxRV", "TauxGLB", "AgeAppTP", "DureePension",
+	"AgeMoyenCat", "RisqueAge", "NiveauRisquePredit", "Wilaya",
+}
+
+// ParseRow converts a worksheet row into a PensionData record using the
+// column positions resolved from the header row
+func ParseRow(row []string, index HeaderIndex, dates *DateParser) (*domain.PensionData, error) {
+	pensionData, errs := ValidateRow(row, index, dates)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return pensionData, nil
+}
+
+// ValidateRow parses every mapped field of row and returns all field errors
+// instead of stopping at the first one
+func ValidateRow(row []string, index HeaderIndex, dates *DateParser) (*domain.PensionData, []*FieldError) {
+	pensionData := &domain.PensionData{}
+	var errs []*FieldError
+	parsed := make(map[string]bool, len(index))
+
+	for _, field := range fieldOrder {
+		pos, ok := index[field]
+		if !ok {
+			continue
+		}
+
+		value := cellValue(row, pos)
+		var err error
+		if set, ok := dateFields[field]; ok {
+			var t time.Time
+			if t, err = dates.Parse(value); err == nil {
+				set(pensionData, t)
+			}
+		} else {
+			err = fieldParsers[field](pensionData, value)
+		}
+
+		if err != nil {
+			errs = append(errs, &FieldError{Field: field, Err: err})
+		} else {
+			parsed[field] = true
+		}
+	}
+
+	return pensionData, append(errs, checkDates(pensionData, parsed)...)
+}
+
+// checkDates runs the sanity checks on the dates that parsed: a birth date
+// cannot be in the future and the jouissance date must follow it
+func checkDates(p *domain.PensionData, parsed map[string]bool) []*FieldError {
+	var errs []*FieldError
+	if parsed["DateNais"] && p.DateNais.After(time.Now()) {
+		errs = append(errs, &FieldError{Field: "DateNais", Err: errors.New("birth date is in the future")})
+	}
+	if parsed["DateNais"] && parsed["DateJouis"] && !p.DateJouis.After(p.DateNais) {
+		errs = append(errs, &FieldError{Field: "DateJouis", Err: errors.New("jouissance date is not after birth date")})
+	}
+	return errs
+}
+
+// cellValue returns the trimmed cell at pos, or "" when the row is shorter
+func cellValue(row []string, pos int) string {
+	if pos < len(row) {
+		return strings.TrimSpace(row[pos])
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+package importer
+
+import (
+	"cnr-tp/domain"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// sheetNamePattern matches sheet names such as "16", "16 - Alger" or "16_Alger".
+// The code must be followed by a separator, a space or nothing, so that names
+// such as "2023" are not read as a code.
+var sheetNamePattern = regexp.MustCompile(`^\s*(\d{1,2})(?:\s*[-_.:]\s*|\s+|$)(.*?)\s*$`)
+
+// sheetGeography splits a sheet name into an AG code and a wilaya name. A
+// name without a leading code, such as "Alger", is looked up in the
+// geography. Either part may be empty.
+func sheetGeography(name string, ref *domain.ReferenceData) (ag, wilaya string) {
+	if m := sheetNamePattern.FindStringSubmatch(name); m != nil {
+		return m[1], m[2]
+	}
+	name = strings.TrimSpace(name)
+	if agency, ok := sheetAgency(name, ref); ok {
+		return strconv.Itoa(int(agency.Code)), name
+	}
+	return "", name
+}
+
+// sheetAgency finds the agency named by a sheet, ignoring case, accents and
+// punctuation. A wilaya name stands for the agency sharing the wilaya code,
+// or for its only agency. Names matching several agencies match none.
+func sheetAgency(name string, ref *domain.ReferenceData) (domain.Agency, bool) {
+	key := normalizeHeader(name)
+	if key == "" {
+		return domain.Agency{}, false
+	}
+
+	for _, w := range ref.Wilayas {
+		if normalizeHeader(w.Name) != key {
+			continue
+		}
+		var agencies []domain.Agency
+		for _, a := range ref.Agencies {
+			if a.WilayaCode != w.Code {
+				continue
+			}
+			if int(a.Code) == w.Code {
+				return a, true
+			}
+			agencies = append(agencies, a)
+		}
+		if len(agencies) == 1 {
+			return agencies[0], true
+		}
+		return domain.Agency{}, false
+	}
+
+	var found []domain.Agency
+	for _, a := range ref.Agencies {
+		if normalizeHeader(a.Name) == key {
+			found = append(found, a)
+		}
+	}
+	if len(found) == 1 {
+		return found[0], true
+	}
+	return domain.Agency{}, false
+}
+
+// constantColumns appends the same cells to every row of a reader, so that
+// values derived from the sheet go through the regular column mapping
+type constantColumns struct {
+	RowReader
+	names  []string
+	values []string
+}
+
+func withConstants(r RowReader, names, values []string) RowReader {
+	if len(names) == 0 {
+		return r
+	}
+	return &constantColumns{RowReader: r, names: names, values: values}
+}
+
+func (c *constantColumns) Header() []string {
+	header := append([]string(nil), c.RowReader.Header()...)
+	return append(header, c.names...)
+}
+
+func (c *constantColumns) Next() ([]string, error) {
+	row, err := c.RowReader.Next()
+	if row == nil {
+		return row, err
+	}
+
+	width := len(c.RowReader.Header())
+	out := make([]string, width, width+len(c.values))
+	copy(out, row)
+	return append(out, c.values...), err
+}
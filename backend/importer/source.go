@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"cnr-tp/domain"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// RowReader yields the records of a tabular source one at a time. Header
+// returns the column names; Next returns io.EOF once the source is exhausted.
+type RowReader interface {
+	Header() []string
+	Next() ([]string, error)
+	Close() error
+}
+
+// RowError is returned by Next for a record that could not be decoded. The
+// pipeline rejects that row and carries on with the next one.
+type RowError struct {
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return e.Err.Error()
+}
+
+var formatExtensions = map[string]domain.ImportFormat{
+	".xlsx":   domain.ImportFormatXLSX,
+	".xlsm":   domain.ImportFormatXLSX,
+	".csv":    domain.ImportFormatCSV,
+	".txt":    domain.ImportFormatCSV,
+	".tsv":    domain.ImportFormatTSV,
+	".tab":    domain.ImportFormatTSV,
+	".ndjson": domain.ImportFormatNDJSON,
+	".jsonl":  domain.ImportFormatNDJSON,
+}
+
+// DetectFormat returns the explicit format when set, otherwise derives it
+// from the file extension
+func DetectFormat(fileName string, format domain.ImportFormat) (domain.ImportFormat, error) {
+	switch format {
+	case domain.ImportFormatXLSX, domain.ImportFormatCSV, domain.ImportFormatTSV, domain.ImportFormatNDJSON:
+		return format, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported import format %q", format)
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if f, ok := formatExtensions[ext]; ok {
+		return f, nil
+	}
+	return "", fmt.Errorf("unsupported file extension %q", ext)
+}
+
+// Source is an opened import file made of one or more sheets. Delimited and
+// NDJSON files have a single unnamed sheet.
+type Source interface {
+	Sheets() []string
+	OpenSheet(name string) (RowReader, error)
+	Close() error
+}
+
+// OpenSource opens filePath with the reader matching its format
+func OpenSource(filePath string, opts domain.ImportOptions) (Source, error) {
+	format, err := DetectFormat(filePath, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader RowReader
+	switch format {
+	case domain.ImportFormatCSV:
+		delimiter := opts.Delimiter
+		if delimiter == "" {
+			delimiter = ";"
+		}
+		reader, err = openDelimited(filePath, delimiter, opts.Encoding)
+	case domain.ImportFormatTSV:
+		reader, err = openDelimited(filePath, "\t", opts.Encoding)
+	case domain.ImportFormatNDJSON:
+		reader, err = openNDJSON(filePath)
+	default:
+		return openExcel(filePath, opts.Passwords)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &singleSource{reader: reader}, nil
+}
+
+// selectSheets returns the requested sheets of source, or all of them
+func selectSheets(source Source, names []string) ([]string, error) {
+	available := source.Sheets()
+	if len(names) == 0 {
+		return available, nil
+	}
+
+	var missing []string
+	for _, name := range names {
+		found := false
+		for _, sheet := range available {
+			if sheet == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("sheet(s) not found: %s", strings.Join(missing, ", "))
+	}
+	return names, nil
+}
+
+// singleSource adapts a single-table reader to the Source interface
+type singleSource struct {
+	reader RowReader
+	opened bool
+}
+
+func (s *singleSource) Sheets() []string {
+	return []string{""}
+}
+
+func (s *singleSource) OpenSheet(name string) (RowReader, error) {
+	if name != "" || s.opened {
+		return nil, fmt.Errorf("sheet %q not found", name)
+	}
+	s.opened = true
+	return nopCloseReader{s.reader}, nil
+}
+
+func (s *singleSource) Close() error {
+	return s.reader.Close()
+}
+
+// nopCloseReader leaves closing the underlying file to its Source
+type nopCloseReader struct {
+	RowReader
+}
+
+func (nopCloseReader) Close() error {
+	return nil
+}
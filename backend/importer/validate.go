@@ -0,0 +1,174 @@
+package importer
+
+import (
+	"cnr-tp/domain"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxValidationSamples caps the failing rows returned by a dry run
+const maxValidationSamples = 20
+
+// numericFields are the fields profiled with min/max values
+var numericFields = map[string]bool{
+	"AG": true, "NetMens": true, "TauxD": true, "TauxRV": true, "TauxGLB": true,
+	"AgeAppTP": true, "DureePension": true, "AgeMoyenCat": true, "RisqueAge": true,
+	"NiveauRisquePredit": true,
+}
+
+// Validate runs the parsing and validation rules of ImportFile over filePath
+// without writing anything to the database, and profiles every mapped column.
+// Column profiles are aggregated across the selected sheets.
+func (im *Importer) Validate(filePath string, opts domain.ImportOptions) (*domain.ValidationReport, error) {
+	opts = im.withDefaults(opts)
+
+	source, err := OpenSource(filePath, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := source.Close(); err != nil {
+			log.Printf("error closing import file: %v", err)
+		}
+	}()
+
+	sheets, err := selectSheets(source, opts.Sheets)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := im.referenceData()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &domain.ValidationReport{FileName: filepath.Base(filePath)}
+	// Position of each profiled field in report.Columns
+	profiles := make(map[string]int)
+	for _, sheet := range sheets {
+		if err := im.validateSheet(source, sheet, opts, ref, report, profiles); err != nil {
+			if sheet != "" {
+				return nil, fmt.Errorf("sheet %s: %v", sheet, err)
+			}
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// validateSheet adds the rows of one sheet to report
+func (im *Importer) validateSheet(source Source, sheet string, opts domain.ImportOptions, ref *domain.ReferenceData, report *domain.ValidationReport, profiles map[string]int) error {
+	reader, err := im.openSheet(source, sheet, opts, ref)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Printf("error closing sheet %s: %v", sheet, err)
+		}
+	}()
+
+	header := reader.Header()
+	index, err := im.mapping.Resolve(header)
+	if err != nil {
+		return err
+	}
+
+	var fields []string
+	for _, field := range fieldOrder {
+		pos, ok := index[field]
+		if !ok {
+			continue
+		}
+		fields = append(fields, field)
+		if _, seen := profiles[field]; !seen {
+			profiles[field] = len(report.Columns)
+			report.Columns = append(report.Columns, domain.ColumnProfile{Field: field, Column: header[pos]})
+		}
+	}
+
+	dates := dateParserFor(source, opts.DateLayouts)
+	sample := func(rowNum int, column, reason string, row []string) {
+		if len(report.Samples) < maxValidationSamples {
+			report.Samples = append(report.Samples, domain.RejectedRow{Sheet: sheet, Row: rowNum, Column: column, Reason: reason, Values: row})
+		}
+	}
+
+	// Row numbers follow the source file, the header being row 1
+	for rowNum := 2; ; rowNum++ {
+		row, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowErr *RowError
+		if err != nil && !errors.As(err, &rowErr) {
+			return fmt.Errorf("failed to read row %d: %v", rowNum, err)
+		}
+		report.Rows++
+
+		if err != nil {
+			report.InvalidRows++
+			sample(rowNum, "", err.Error(), nil)
+			continue
+		}
+
+		data, errs := ValidateRow(row, index, dates)
+		failed := make(map[string]bool, len(errs))
+		for _, e := range errs {
+			failed[e.Field] = true
+		}
+		if !failed["AG"] {
+			if err := locateAgency(data, index, ref); err != nil {
+				errs = append(errs, err)
+				failed["AG"] = true
+			}
+		}
+
+		for _, field := range fields {
+			profile := &report.Columns[profiles[field]]
+			if failed[field] {
+				profile.Errors++
+			}
+
+			value := cellValue(row, index[field])
+			if value == "" {
+				profile.Nulls++
+				continue
+			}
+			if numericFields[field] && !failed[field] {
+				if n, err := strconv.ParseFloat(value, 64); err == nil {
+					if profile.Min == nil || n < *profile.Min {
+						minValue := n
+						profile.Min = &minValue
+					}
+					if profile.Max == nil || n > *profile.Max {
+						maxValue := n
+						profile.Max = &maxValue
+					}
+				}
+			}
+		}
+
+		if len(errs) == 0 {
+			report.ValidRows++
+			continue
+		}
+
+		report.InvalidRows++
+		columns := make([]string, 0, len(errs))
+		reasons := make([]string, 0, len(errs))
+		for _, e := range errs {
+			columns = append(columns, header[index[e.Field]])
+			reasons = append(reasons, e.Error())
+		}
+		sample(rowNum, strings.Join(columns, ", "), strings.Join(reasons, "; "), row)
+	}
+
+	return nil
+}
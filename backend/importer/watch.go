@@ -0,0 +1,211 @@
+package importer
+
+import (
+	"cnr-tp/domain"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	processedDir = "processed"
+	failedDir    = "failed"
+	// Rejected rows reports are written here until the file is moved
+	stagingDir = ".reports"
+)
+
+// WatchResult is the sidecar summary written next to each file moved out of
+// the watched directory
+type WatchResult struct {
+	File       string              `json:"file"`
+	SHA256     string              `json:"sha256"`
+	Status     domain.ImportStatus `json:"status"`
+	Error      string              `json:"error,omitempty"`
+	Duplicate  bool                `json:"duplicate,omitempty"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt time.Time           `json:"finished_at"`
+	domain.ImportSummary
+}
+
+// fileState is the size and modification time of a file at the last poll
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Watcher polls a drop directory and imports the files placed in it. Each
+// file is moved to processed/ or failed/ once handled, with a <name>.json
+// sidecar summary, so it is never picked up again. Content already imported
+// under another name is recognised by its checksum and not loaded twice.
+type Watcher struct {
+	importer *Importer
+	dir      string
+	interval time.Duration
+
+	// Files seen at the previous poll, imported once unchanged for a full interval
+	pending map[string]fileState
+	// Checksums of the files in processed/
+	imported map[string]bool
+}
+
+func NewWatcher(im *Importer, dir string, interval time.Duration) *Watcher {
+	return &Watcher{
+		importer: im,
+		dir:      dir,
+		interval: interval,
+		pending:  make(map[string]fileState),
+	}
+}
+
+// Run polls the directory until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) {
+	log.Printf("Watching %s for import files every %s", w.dir, w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.Scan()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan runs one poll: files unchanged since the previous poll are imported,
+// new or still growing ones are remembered for the next one
+func (w *Watcher) Scan() {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading watch directory %s: %v", w.dir, err)
+		}
+		return
+	}
+
+	seen := make(map[string]fileState, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		// Skip subfolders, hidden files and Office lock files
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		if prev, ok := w.pending[name]; !ok || prev != state {
+			// Still being copied, or new: wait for the next poll
+			seen[name] = state
+			continue
+		}
+
+		w.process(name)
+	}
+	w.pending = seen
+}
+
+// process imports one file and moves it out of the watched directory
+func (w *Watcher) process(name string) {
+	path := filepath.Join(w.dir, name)
+	result := WatchResult{File: name, StartedAt: time.Now()}
+
+	sum, err := fileChecksum(path)
+	if err != nil {
+		log.Printf("Error reading %s: %v", path, err)
+		return
+	}
+	result.SHA256 = sum
+
+	destDir := filepath.Join(w.dir, processedDir)
+	if w.alreadyImported(sum) {
+		log.Printf("Skipping %s: the same content was already imported", name)
+		result.Status = domain.ImportStatusCompleted
+		result.Duplicate = true
+	} else {
+		log.Printf("Importing %s", path)
+		opts := domain.ImportOptions{RejectReportPath: w.stagedReport(name)}
+		result.ImportSummary, err = w.importer.ImportFile(path, opts, nil)
+		if err != nil {
+			log.Printf("Failed to import %s: %v", name, err)
+			result.Status = domain.ImportStatusFailed
+			result.Error = err.Error()
+			destDir = filepath.Join(w.dir, failedDir)
+		} else {
+			log.Printf("Successfully imported %s", name)
+			result.Status = domain.ImportStatusCompleted
+			w.imported[sum] = true
+		}
+	}
+	result.FinishedAt = time.Now()
+
+	if err := w.move(name, destDir, result); err != nil {
+		log.Printf("Error moving %s to %s: %v", name, destDir, err)
+	}
+}
+
+// move puts the file, its sidecar summary and its rejected rows report into
+// destDir. A file of the same name already there is kept, and the new one is
+// prefixed with a timestamp.
+func (w *Watcher) move(name, destDir string, result WatchResult) error {
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return err
+	}
+
+	dest := name
+	if _, err := os.Stat(filepath.Join(destDir, dest)); err == nil {
+		dest = result.StartedAt.Format("20060102-150405") + "_" + name
+	}
+
+	report := w.stagedReport(name)
+	if _, err := os.Stat(report); err == nil {
+		if err := os.Rename(report, filepath.Join(destDir, dest+"_rejected.xlsx")); err != nil {
+			log.Printf("Error moving rejected rows report of %s: %v", name, err)
+		}
+	}
+
+	sidecar, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(destDir, dest+".json"), sidecar, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(filepath.Join(w.dir, name), filepath.Join(destDir, dest))
+}
+
+// stagedReport is the path of the rejected rows report of name until it is moved
+func (w *Watcher) stagedReport(name string) string {
+	return filepath.Join(w.dir, stagingDir, name+"_rejected.xlsx")
+}
+
+// alreadyImported reports whether a file with checksum sum was imported
+// successfully, loading the processed/ sidecars on first use
+func (w *Watcher) alreadyImported(sum string) bool {
+	if w.imported == nil {
+		w.imported = make(map[string]bool)
+
+		sidecars, _ := filepath.Glob(filepath.Join(w.dir, processedDir, "*.json"))
+		for _, path := range sidecars {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			var result WatchResult
+			if err := json.Unmarshal(data, &result); err == nil && result.SHA256 != "" {
+				w.imported[result.SHA256] = true
+			}
+		}
+	}
+	return w.imported[sum]
+}
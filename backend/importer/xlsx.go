@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// oleSignature starts the compound file wrapping an encrypted workbook
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// excelSource gives access to the sheets of a workbook
+type excelSource struct {
+	file     *excelize.File
+	date1904 bool
+}
+
+// openExcel opens a workbook. An encrypted workbook is opened with the first
+// of passwords that decrypts it.
+func openExcel(filePath string, passwords []string) (Source, error) {
+	encrypted, err := isEncrypted(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open excel file: %v", err)
+	}
+
+	var f *excelize.File
+	if encrypted {
+		if f, err = openEncrypted(filePath, passwords); err != nil {
+			return nil, err
+		}
+	} else if f, err = excelize.OpenFile(filePath); err != nil {
+		return nil, fmt.Errorf("failed to open excel file: %v", err)
+	}
+
+	if len(f.GetSheetList()) == 0 {
+		f.Close()
+		return nil, errors.New("no sheets found in the excel file")
+	}
+
+	props, err := f.GetWorkbookProps()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read workbook properties: %v", err)
+	}
+	date1904 := props.Date1904 != nil && *props.Date1904
+
+	return &excelSource{file: f, date1904: date1904}, nil
+}
+
+func openEncrypted(filePath string, passwords []string) (*excelize.File, error) {
+	if len(passwords) == 0 {
+		return nil, errors.New("excel file is password protected and no password was supplied")
+	}
+	for _, password := range passwords {
+		if f, err := excelize.OpenFile(filePath, excelize.Options{Password: password}); err == nil {
+			return f, nil
+		}
+	}
+	return nil, errors.New("excel file is password protected and none of the supplied passwords is correct")
+}
+
+// isEncrypted reports whether filePath is an encrypted workbook rather than a
+// plain xlsx archive
+func isEncrypted(filePath string) (bool, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	head := make([]byte, len(oleSignature))
+	if _, err := io.ReadFull(f, head); err != nil {
+		// Too short to be encrypted; let excelize report the error
+		return false, nil
+	}
+	return bytes.Equal(head, oleSignature), nil
+}
+
+func (s *excelSource) Sheets() []string {
+	return s.file.GetSheetList()
+}
+
+func (s *excelSource) OpenSheet(name string) (RowReader, error) {
+	log.Printf("Processing sheet: %s", name)
+
+	rows, err := s.file.Rows(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rows from sheet %s: %v", name, err)
+	}
+
+	r := &excelReader{rows: rows}
+	header, err := r.Next()
+	if err != nil {
+		r.Close()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("sheet %s is empty", name)
+		}
+		return nil, err
+	}
+	r.header = header
+
+	return r, nil
+}
+
+// Date1904 reports whether serial dates count from 1904-01-01
+func (s *excelSource) Date1904() bool {
+	return s.date1904
+}
+
+func (s *excelSource) Close() error {
+	return s.file.Close()
+}
+
+// excelReader streams one sheet row by row. Large sheets are unpacked to
+// temporary files by excelize rather than held in memory.
+type excelReader struct {
+	rows   *excelize.Rows
+	header []string
+}
+
+func (r *excelReader) Header() []string {
+	return r.header
+}
+
+func (r *excelReader) Next() ([]string, error) {
+	if !r.rows.Next() {
+		if err := r.rows.Error(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	// Raw values keep dates as serial numbers rather than locale-formatted text
+	return r.rows.Columns(excelize.Options{RawCellValue: true})
+}
+
+func (r *excelReader) Close() error {
+	return r.rows.Close()
+}
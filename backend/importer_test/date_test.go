@@ -0,0 +1,67 @@
+package importer_test
+
+import (
+	"cnr-tp/importer"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateParser_Parse(t *testing.T) {
+	want := time.Date(1950, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	// Test: every supported format yields the same date
+	for _, v := range []string{"1950-03-01 00:00:00", "1950-03-01", "01/03/1950", "1/3/1950", "01-03-1950", "01.03.1950"} {
+		got, err := importer.NewDateParser(nil).Parse(v)
+		assert.NoError(t, err, "Failed to parse %q", v)
+		assert.True(t, want.Equal(got), "Parsed %q as %v", v, got)
+	}
+
+	// Test: serial numbers are only dates in workbooks, in their date system
+	got, err := importer.NewWorkbookDateParser(nil, false).Parse("18323")
+	assert.NoError(t, err)
+	assert.True(t, want.Equal(got), "Parsed serial as %v", got)
+	got, err = importer.NewWorkbookDateParser(nil, true).Parse("16861")
+	assert.NoError(t, err)
+	assert.True(t, want.Equal(got), "Parsed 1904 serial as %v", got)
+
+	for _, v := range []string{"18323", "1935"} {
+		_, err = importer.NewDateParser(nil).Parse(v)
+		assert.Error(t, err, "Numbers outside workbooks are not dates: %q", v)
+	}
+
+	// Test: a nil parser uses the built-in formats
+	_, err = (*importer.DateParser)(nil).Parse("01/03/1950")
+	assert.NoError(t, err)
+
+	// Test: configured layouts are tried first
+	got, err = importer.NewDateParser([]string{"01/02/2006"}).Parse("03/01/1950")
+	assert.NoError(t, err)
+	assert.True(t, want.Equal(got), "Configured layout should win, got %v", got)
+
+	_, err = importer.NewDateParser(nil).Parse("not a date")
+	assert.Error(t, err)
+	_, err = importer.NewWorkbookDateParser(nil, false).Parse("-5")
+	assert.Error(t, err, "Expected out of range serial error")
+}
+
+func TestValidateRow_DateChecks(t *testing.T) {
+	index := importer.HeaderIndex{"DateNais": 0, "DateJouis": 1}
+
+	_, errs := importer.ValidateRow([]string{"01/03/1950", "01/01/2010"}, index, nil)
+	assert.Empty(t, errs)
+
+	// Test: a birth date in the future is rejected
+	future := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+	_, errs = importer.ValidateRow([]string{future, ""}, importer.HeaderIndex{"DateNais": 0}, nil)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "DateNais", errs[0].Field)
+	}
+
+	// Test: the jouissance date must follow the birth date
+	_, errs = importer.ValidateRow([]string{"01/03/1950", "01/03/1940"}, index, nil)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "DateJouis", errs[0].Field)
+	}
+}
@@ -0,0 +1,264 @@
+package importer_test
+
+import (
+	"cnr-tp/domain"
+	"cnr-tp/importer"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+// memoryPensionUseCase keeps the inserted records in memory. Transactions
+// write to a copy that is only kept when the function succeeds. Inserts
+// holding a pension number of existing fail as a whole, like a multi-row
+// INSERT hitting the unique natural key.
+type memoryPensionUseCase struct {
+	domain.PensionUseCase
+	existing   map[string]bool
+	stored     []domain.PensionData
+	chunks     []int
+	batchSizes []int
+	rollbacks  int
+}
+
+func (m *memoryPensionUseCase) CreatePensions(pensions []domain.PensionData, batchSize int) error {
+	for _, p := range pensions {
+		if m.existing[p.NPens] {
+			return fmt.Errorf("duplicate entry '%s' for key 'uniq_pension_natural_key'", p.NPens)
+		}
+	}
+	m.stored = append(m.stored, pensions...)
+	m.chunks = append(m.chunks, len(pensions))
+	m.batchSizes = append(m.batchSizes, batchSize)
+	return nil
+}
+
+func (m *memoryPensionUseCase) Transaction(fn func(uc domain.PensionUseCase) error) error {
+	tx := &memoryPensionUseCase{existing: m.existing}
+	if err := fn(tx); err != nil {
+		m.rollbacks++
+		return err
+	}
+	m.stored = append(m.stored, tx.stored...)
+	m.chunks = append(m.chunks, tx.chunks...)
+	m.batchSizes = append(m.batchSizes, tx.batchSizes...)
+	return nil
+}
+
+// writePensionCSV writes a file of valid rows followed by rows rejected for
+// their NetMens
+func writePensionCSV(t *testing.T, valid, invalid int) string {
+	t.Helper()
+
+	var b strings.Builder
+	b.WriteString("AG;AVT;NPens;EtatPens;DateNais;DateJouis;SexeTP;NetMens;TauxD;TauxRV;TauxGLB;AgeAppTP;DureePension;AgeMoyenCat;RisqueAge;NiveauRisquePredit\n")
+	for i := 0; i < valid+invalid; i++ {
+		netMens := "15000"
+		if i >= valid {
+			netMens = "abc"
+		}
+		fmt.Fprintf(&b, "16;1;P%d;A;1950-03-01;2010-01-01;M;%s;80;0;80;60;14;3;1;2\n", i, netMens)
+	}
+
+	path := filepath.Join(t.TempDir(), "pensions.csv")
+	assert.NoError(t, os.WriteFile(path, []byte(b.String()), 0o644))
+	return path
+}
+
+func newTestImporter(t *testing.T, uc domain.PensionUseCase, defaults domain.ImportOptions) *importer.Importer {
+	t.Helper()
+
+	mapping, err := importer.LoadMapping("../config/column_mapping.json")
+	assert.NoError(t, err, "Failed to load column mapping")
+	defaults.Delimiter = ";"
+	return importer.New(uc, nil, nil, mapping, defaults)
+}
+
+func TestImportFile_AtomicRejectRate(t *testing.T) {
+	rate := func(r float64) *float64 { return &r }
+
+	for _, tc := range []struct {
+		name           string
+		valid, invalid int
+		maxRejectRate  float64
+		committed      bool
+	}{
+		{"below the threshold", 9, 1, 0.2, true},
+		{"at the threshold", 3, 1, 0.25, true},
+		{"above the threshold", 1, 2, 0.5, false},
+	} {
+		uc := &memoryPensionUseCase{}
+		im := newTestImporter(t, uc, domain.ImportOptions{})
+		path := writePensionCSV(t, tc.valid, tc.invalid)
+
+		summary, err := im.ImportFile(path, domain.ImportOptions{Mode: domain.ImportModeAtomic, MaxRejectRate: rate(tc.maxRejectRate)}, nil)
+		assert.Equal(t, tc.valid+tc.invalid, summary.RowsProcessed, tc.name)
+		assert.Equal(t, tc.invalid, summary.RowsRejected, tc.name)
+
+		if tc.committed {
+			assert.NoError(t, err, tc.name)
+			assert.Len(t, uc.stored, tc.valid, tc.name)
+			assert.Equal(t, tc.valid, summary.RowsInserted, tc.name)
+			assert.Zero(t, uc.rollbacks, tc.name)
+			continue
+		}
+
+		// A rolled back import stores nothing and reports no written rows
+		assert.ErrorContains(t, err, "import rolled back", tc.name)
+		assert.ErrorContains(t, err, "exceeds threshold", tc.name)
+		assert.Empty(t, uc.stored, tc.name)
+		assert.Equal(t, 1, uc.rollbacks, tc.name)
+		assert.Zero(t, summary.RowsInserted, tc.name)
+		assert.Zero(t, summary.RowsUpdated, tc.name)
+		assert.Zero(t, summary.RowsUnchanged, tc.name)
+	}
+}
+
+func TestImportFile_BestEffortKeepsValidRows(t *testing.T) {
+	uc := &memoryPensionUseCase{}
+	im := newTestImporter(t, uc, domain.ImportOptions{})
+
+	// Best effort mode ignores the reject rate
+	summary, err := im.ImportFile(writePensionCSV(t, 1, 2), domain.ImportOptions{}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, uc.stored, 1)
+	assert.Equal(t, 1, summary.RowsInserted)
+	assert.Equal(t, 2, summary.RowsRejected)
+}
+
+func TestImportFile_WriteErrorRejectsOnlyFailingRows(t *testing.T) {
+	uc := &memoryPensionUseCase{existing: map[string]bool{"P2": true}}
+	im := newTestImporter(t, uc, domain.ImportOptions{BatchSize: 5})
+	reportPath := filepath.Join(t.TempDir(), "job"+importer.RejectReportSuffix)
+
+	// Test: the failed chunk is retried row by row, and only the stored key is rejected
+	summary, err := im.ImportFile(writePensionCSV(t, 5, 0), domain.ImportOptions{RejectReportPath: reportPath}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, summary.RowsInserted)
+	assert.Equal(t, 1, summary.RowsRejected)
+	var stored []string
+	for _, p := range uc.stored {
+		stored = append(stored, p.NPens)
+	}
+	assert.Equal(t, []string{"P0", "P1", "P3", "P4"}, stored)
+
+	f, err := excelize.OpenFile(reportPath)
+	assert.NoError(t, err)
+	defer f.Close()
+	rows, err := f.GetRows("Rejected rows")
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, "4", rows[1][0], "P2 is the fourth line of the file")
+		assert.Contains(t, rows[1][2], "write error")
+	}
+}
+
+func TestImportFile_BatchSize(t *testing.T) {
+	// Test: the configured batch size applies when the request sets none
+	uc := &memoryPensionUseCase{}
+	im := newTestImporter(t, uc, domain.ImportOptions{BatchSize: 3})
+	_, err := im.ImportFile(writePensionCSV(t, 7, 0), domain.ImportOptions{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 3, 1}, uc.chunks)
+	assert.Equal(t, []int{3, 3, 3}, uc.batchSizes)
+
+	// Test: the batch size of the request overrides it
+	uc = &memoryPensionUseCase{}
+	im = newTestImporter(t, uc, domain.ImportOptions{BatchSize: 3})
+	_, err = im.ImportFile(writePensionCSV(t, 7, 0), domain.ImportOptions{BatchSize: 5}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5, 2}, uc.chunks)
+	assert.Equal(t, []int{5, 5}, uc.batchSizes)
+	assert.Len(t, uc.stored, 7)
+}
+
+func TestImportFile_Progress(t *testing.T) {
+	uc := &memoryPensionUseCase{}
+	im := newTestImporter(t, uc, domain.ImportOptions{BatchSize: 4})
+
+	// Test: progress is reported every ProgressEvery rows, rejected ones included
+	var processed []int
+	summary, err := im.ImportFile(writePensionCSV(t, 8, 2), domain.ImportOptions{ProgressEvery: 3}, func(s domain.ImportSummary) {
+		processed = append(processed, s.RowsProcessed)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 6, 9}, processed)
+	assert.Equal(t, 10, summary.RowsProcessed)
+	assert.Len(t, uc.stored, 8)
+}
+
+func TestImportFile_WriteFailureStopsReader(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	// Test: a failed write in atomic mode ends the import without reading the
+	// rest of the file
+	uc := &memoryPensionUseCase{existing: map[string]bool{"P0": true}}
+	im := newTestImporter(t, uc, domain.ImportOptions{BatchSize: 2})
+	summary, err := im.ImportFile(writePensionCSV(t, 1000, 0), domain.ImportOptions{Mode: domain.ImportModeAtomic}, nil)
+	assert.ErrorContains(t, err, "batch write failed")
+	assert.Equal(t, 2, summary.RowsProcessed, "Rows after the failed batch should not be processed")
+	assert.Empty(t, uc.stored)
+
+	// Test: the row reader has exited, as the rows channel would otherwise
+	// have kept it blocked
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before, "Row reader goroutine leaked")
+}
+
+func TestImportFile_WorkbookDateSystem(t *testing.T) {
+	header := []interface{}{
+		"AG", "AVT", "NPens", "EtatPens", "DateNais", "DateJouis", "SexeTP", "NetMens", "TauxD", "TauxRV",
+		"TauxGLB", "AgeAppTP", "DureePension", "AgeMoyenCat", "RisqueAge", "NiveauRisquePredit",
+	}
+	want := time.Date(1950, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		date1904 bool
+		serial   int
+	}{
+		{false, 18323},
+		{true, 16861},
+	} {
+		f := excelize.NewFile()
+		date1904 := tc.date1904
+		assert.NoError(t, f.SetWorkbookProps(&excelize.WorkbookPropsOptions{Date1904: &date1904}))
+		assert.NoError(t, f.SetSheetRow("Sheet1", "A1", &header))
+		assert.NoError(t, f.SetSheetRow("Sheet1", "A2", &[]interface{}{
+			16, "1", "P1", "A", tc.serial, "2010-01-01", "M", 15000, 80, 0, 80, 60, 14, 3, 1, 2,
+		}))
+		path := filepath.Join(t.TempDir(), "pensions.xlsx")
+		assert.NoError(t, f.SaveAs(path))
+		f.Close()
+
+		uc := &memoryPensionUseCase{}
+		_, err := newTestImporter(t, uc, domain.ImportOptions{}).ImportFile(path, domain.ImportOptions{}, nil)
+		assert.NoError(t, err)
+		if assert.Len(t, uc.stored, 1) {
+			assert.True(t, want.Equal(uc.stored[0].DateNais), "1904 system %v: parsed %v", tc.date1904, uc.stored[0].DateNais)
+		}
+	}
+}
+
+func TestImportFile_NumericDateInCSV(t *testing.T) {
+	content := "AG;AVT;NPens;EtatPens;DateNais;DateJouis;SexeTP;NetMens;TauxD;TauxRV;TauxGLB;AgeAppTP;DureePension;AgeMoyenCat;RisqueAge;NiveauRisquePredit\n" +
+		"16;1;P1;A;1935;2010-01-01;M;15000;80;0;80;60;14;3;1;2\n"
+	path := filepath.Join(t.TempDir(), "pensions.csv")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	// Test: a bare year is rejected rather than read as an Excel serial
+	uc := &memoryPensionUseCase{}
+	summary, err := newTestImporter(t, uc, domain.ImportOptions{}).ImportFile(path, domain.ImportOptions{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, summary.RowsRejected)
+	assert.Empty(t, uc.stored)
+}
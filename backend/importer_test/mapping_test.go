@@ -0,0 +1,46 @@
+package importer_test
+
+import (
+	"cnr-tp/importer"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnMapping_Resolve(t *testing.T) {
+	mapping, err := importer.LoadMapping("../config/column_mapping.json")
+	assert.NoError(t, err, "Failed to load column mapping")
+
+	header := []string{
+		"Commentaire", "Net Mensuel", "AG", "AVT", "NPens", "Etat Pension", "Date de naissance",
+		"DateJouis", "Sexe", "TauxD", "TauxRV", "Taux Global", "AgeAppTP", "Durée Pension",
+		"AgeMoyenCat", "RisqueAge", "NiveauRisquePredit",
+	}
+
+	// Test: reordered, aliased and extra columns are resolved
+	index, err := mapping.Resolve(header)
+	assert.NoError(t, err, "Failed to resolve header")
+	assert.Equal(t, 1, index["NetMens"])
+	assert.Equal(t, 2, index["AG"])
+	assert.Equal(t, 11, index["TauxGLB"])
+	assert.Equal(t, 13, index["DureePension"])
+	_, hasWilaya := index["Wilaya"]
+	assert.False(t, hasWilaya, "Optional column should not be resolved")
+
+	row := []string{
+		"ignored", "15000.50", "16", "1", "P001", "A", "1950-03-01 00:00:00",
+		"2010-01-01 00:00:00", "M", "80", "0", "80", "60", "14",
+		"3", "1", "2",
+	}
+	pension, err := importer.ParseRow(row, index, nil)
+	assert.NoError(t, err, "Failed to parse row")
+	assert.Equal(t, int8(16), pension.AG)
+	assert.Equal(t, "P001", pension.NPens)
+	assert.InDelta(t, 15000.50, pension.NetMens, 0.001)
+	assert.Equal(t, 14, pension.DureePension)
+
+	// Test: missing required columns are reported by name
+	_, err = mapping.Resolve([]string{"AG", "AVT"})
+	assert.Error(t, err, "Expected error when required columns are missing")
+	assert.Contains(t, err.Error(), "NetMens")
+}
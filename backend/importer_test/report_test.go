@@ -0,0 +1,78 @@
+package importer_test
+
+import (
+	"cnr-tp/domain"
+	"cnr-tp/importer"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestRejectReport_Content(t *testing.T) {
+	uc := &memoryPensionUseCase{}
+	im := newTestImporter(t, uc, domain.ImportOptions{})
+	reportPath := filepath.Join(t.TempDir(), "reports", "job"+importer.RejectReportSuffix)
+
+	_, err := im.ImportFile(writePensionCSV(t, 1, 1), domain.ImportOptions{RejectReportPath: reportPath}, nil)
+	assert.NoError(t, err)
+
+	f, err := excelize.OpenFile(reportPath)
+	assert.NoError(t, err, "Rejected rows report should be written")
+	defer f.Close()
+
+	assert.Equal(t, []string{"Rejected rows"}, f.GetSheetList())
+	rows, err := f.GetRows("Rejected rows")
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+
+	// Diagnostic columns come first, then the original header and cells
+	assert.Equal(t, []string{"Row Number", "Failing Column", "Reason", "AG", "AVT", "NPens"}, rows[0][:6])
+	assert.Equal(t, "3", rows[1][0], "The rejected row is the third line of the file")
+	assert.Equal(t, "NetMens", rows[1][1])
+	assert.Contains(t, rows[1][2], "abc")
+	assert.Equal(t, []string{"16", "1", "P1", "A", "1950-03-01", "2010-01-01", "M", "abc"}, rows[1][3:11])
+}
+
+func TestRejectReport_NotWrittenWithoutRejects(t *testing.T) {
+	im := newTestImporter(t, &memoryPensionUseCase{}, domain.ImportOptions{})
+	reportPath := filepath.Join(t.TempDir(), "job"+importer.RejectReportSuffix)
+
+	_, err := im.ImportFile(writePensionCSV(t, 2, 0), domain.ImportOptions{RejectReportPath: reportPath}, nil)
+	assert.NoError(t, err)
+	_, err = os.Stat(reportPath)
+	assert.True(t, os.IsNotExist(err), "No report should be written without rejected rows")
+}
+
+func TestPruneRejectReports(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"old" + importer.RejectReportSuffix, "recent" + importer.RejectReportSuffix, "old_notes.xlsx"} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte("x"), 0o644))
+		if name != "recent"+importer.RejectReportSuffix {
+			assert.NoError(t, os.Chtimes(path, old, old))
+		}
+	}
+
+	// Test: only reports older than the cutoff are deleted
+	deleted, err := importer.PruneRejectReports(dir, time.Now().Add(-24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.ElementsMatch(t, []string{"recent" + importer.RejectReportSuffix, "old_notes.xlsx"}, names)
+
+	// Test: a missing directory holds no report
+	deleted, err = importer.PruneRejectReports(filepath.Join(dir, "missing"), time.Now())
+	assert.NoError(t, err)
+	assert.Zero(t, deleted)
+}
@@ -0,0 +1,132 @@
+package importer_test
+
+import (
+	"cnr-tp/domain"
+	"cnr-tp/importer"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/charmap"
+)
+
+func readAll(t *testing.T, r importer.RowReader) [][]string {
+	var rows [][]string
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			return rows
+		}
+		assert.NoError(t, err, "Failed to read row")
+		rows = append(rows, row)
+	}
+}
+
+func TestOpenSource_CSV(t *testing.T) {
+	content, err := charmap.Windows1252.NewEncoder().String("AG;Etat Pension;Net Mensuel\n16;Décès;15000.5\n31;\"A;B\";900\n")
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "extract.csv")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	src, err := importer.OpenSource(path, domain.ImportOptions{Delimiter: ";", Encoding: "windows-1252"})
+	assert.NoError(t, err, "Failed to open CSV source")
+	defer src.Close()
+
+	assert.Equal(t, []string{""}, src.Sheets())
+	r, err := src.OpenSheet("")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"AG", "Etat Pension", "Net Mensuel"}, r.Header())
+	assert.Equal(t, [][]string{{"16", "Décès", "15000.5"}, {"31", "A;B", "900"}}, readAll(t, r))
+}
+
+func TestOpenSource_NDJSON(t *testing.T) {
+	content := `{"NPens":"P1","AG":16,"NetMens":15000.50,"Wilaya":null}
+
+{"AG":31,"NPens":"P2","NetMens":900,"Extra":true}
+not json
+`
+	path := filepath.Join(t.TempDir(), "extract.ndjson")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	src, err := importer.OpenSource(path, domain.ImportOptions{})
+	assert.NoError(t, err, "Failed to open NDJSON source")
+	defer src.Close()
+
+	r, err := src.OpenSheet("")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"NPens", "AG", "NetMens", "Wilaya"}, r.Header())
+
+	row, err := r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"P1", "16", "15000.50", ""}, row)
+
+	row, err = r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"P2", "31", "900", ""}, row)
+
+	// Test: a malformed line is reported as a row error, not a fatal one
+	_, err = r.Next()
+	var rowErr *importer.RowError
+	assert.ErrorAs(t, err, &rowErr)
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestOpenSource_Workbook(t *testing.T) {
+	f := excelize.NewFile()
+	assert.NoError(t, f.SetSheetName("Sheet1", "16 - Alger"))
+	_, err := f.NewSheet("31 Oran")
+	assert.NoError(t, err)
+	assert.NoError(t, f.SetSheetRow("16 - Alger", "A1", &[]interface{}{"NPens", "NetMens"}))
+	assert.NoError(t, f.SetSheetRow("16 - Alger", "A2", &[]interface{}{"P1", 15000}))
+	assert.NoError(t, f.SetSheetRow("31 Oran", "A1", &[]interface{}{"NPens"}))
+
+	path := filepath.Join(t.TempDir(), "agencies.xlsx")
+	assert.NoError(t, f.SaveAs(path))
+	f.Close()
+
+	src, err := importer.OpenSource(path, domain.ImportOptions{})
+	assert.NoError(t, err, "Failed to open workbook")
+	defer src.Close()
+
+	assert.Equal(t, []string{"16 - Alger", "31 Oran"}, src.Sheets())
+
+	r, err := src.OpenSheet("16 - Alger")
+	assert.NoError(t, err)
+	defer r.Close()
+
+	assert.Equal(t, []string{"NPens", "NetMens"}, r.Header())
+	assert.Equal(t, [][]string{{"P1", "15000"}}, readAll(t, r))
+}
+
+func TestOpenSource_EncryptedWorkbook(t *testing.T) {
+	f := excelize.NewFile()
+	assert.NoError(t, f.SetSheetRow("Sheet1", "A1", &[]interface{}{"NPens"}))
+	path := filepath.Join(t.TempDir(), "protected.xlsx")
+	assert.NoError(t, f.SaveAs(path, excelize.Options{Password: "s3cret"}))
+	f.Close()
+
+	// Test: an encrypted workbook needs a password
+	_, err := importer.OpenSource(path, domain.ImportOptions{})
+	assert.ErrorContains(t, err, "password protected")
+
+	_, err = importer.OpenSource(path, domain.ImportOptions{Passwords: []string{"wrong"}})
+	assert.ErrorContains(t, err, "none of the supplied passwords")
+
+	// Test: the passwords are tried in turn
+	src, err := importer.OpenSource(path, domain.ImportOptions{Passwords: []string{"wrong", "s3cret"}})
+	assert.NoError(t, err, "Failed to open encrypted workbook")
+	defer src.Close()
+
+	r, err := src.OpenSheet("Sheet1")
+	assert.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, []string{"NPens"}, r.Header())
+}
@@ -0,0 +1,194 @@
+package importer_test
+
+import (
+	"cnr-tp/domain"
+	"cnr-tp/importer"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestValidate_SheetAsWilaya(t *testing.T) {
+	mapping, err := importer.LoadMapping("../config/column_mapping.json")
+	assert.NoError(t, err, "Failed to load column mapping")
+
+	header := []interface{}{
+		"AVT", "NPens", "EtatPens", "DateNais", "DateJouis", "SexeTP", "NetMens", "TauxD", "TauxRV",
+		"TauxGLB", "AgeAppTP", "DureePension", "AgeMoyenCat", "RisqueAge", "NiveauRisquePredit",
+	}
+	row := func(npens, netMens string) *[]interface{} {
+		return &[]interface{}{
+			"1", npens, "A", "1950-03-01 00:00:00", "2010-01-01 00:00:00", "M", netMens, "80", "0",
+			"80", "60", "14", "3", "1", "2",
+		}
+	}
+
+	// One sheet per agency, without an AG column
+	f := excelize.NewFile()
+	assert.NoError(t, f.SetSheetName("Sheet1", "16 - Alger"))
+	_, err = f.NewSheet("31_Oran")
+	assert.NoError(t, err)
+	for _, sheet := range []string{"16 - Alger", "31_Oran"} {
+		assert.NoError(t, f.SetSheetRow(sheet, "A1", &header))
+		assert.NoError(t, f.SetSheetRow(sheet, "A2", row(sheet+"-P1", "15000")))
+	}
+	assert.NoError(t, f.SetSheetRow("31_Oran", "A3", row("P2", "abc")))
+
+	path := filepath.Join(t.TempDir(), "agencies.xlsx")
+	assert.NoError(t, f.SaveAs(path))
+	f.Close()
+
+	im := importer.New(nil, nil, nil, mapping, domain.ImportOptions{})
+
+	// Test: AG is required, so sheets without it fail unless derived from the name
+	_, err = im.Validate(path, domain.ImportOptions{})
+	assert.Error(t, err, "Expected missing AG column")
+
+	report, err := im.Validate(path, domain.ImportOptions{SheetAsWilaya: true})
+	assert.NoError(t, err, "Failed to validate workbook")
+	assert.Equal(t, 3, report.Rows)
+	assert.Equal(t, 2, report.ValidRows)
+	assert.Equal(t, 1, report.InvalidRows)
+	assert.Equal(t, "31_Oran", report.Samples[0].Sheet)
+	assert.Equal(t, 3, report.Samples[0].Row)
+
+	for _, column := range report.Columns {
+		if column.Field == "AG" {
+			assert.Equal(t, 16.0, *column.Min)
+			assert.Equal(t, 31.0, *column.Max)
+		}
+	}
+
+	// Test: only the selected sheets are read
+	report, err = im.Validate(path, domain.ImportOptions{SheetAsWilaya: true, Sheets: []string{"16 - Alger"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Rows)
+
+	_, err = im.Validate(path, domain.ImportOptions{Sheets: []string{"Missing"}})
+	assert.Error(t, err, "Expected unknown sheet error")
+}
+
+func TestValidate_SheetNamedAfterWilaya(t *testing.T) {
+	mapping, err := importer.LoadMapping("../config/column_mapping.json")
+	assert.NoError(t, err, "Failed to load column mapping")
+
+	header := []interface{}{
+		"AVT", "NPens", "EtatPens", "DateNais", "DateJouis", "SexeTP", "NetMens", "TauxD", "TauxRV",
+		"TauxGLB", "AgeAppTP", "DureePension", "AgeMoyenCat", "RisqueAge", "NiveauRisquePredit",
+	}
+	row := []interface{}{
+		"1", "P1", "A", "1950-03-01 00:00:00", "2010-01-01 00:00:00", "M", "15000", "80", "0",
+		"80", "60", "14", "3", "1", "2",
+	}
+
+	// One sheet per wilaya, named without its code
+	sheets := []string{"Alger", "bejaia", "Tizi-Ouzou", "Atlantis"}
+	f := excelize.NewFile()
+	assert.NoError(t, f.SetSheetName("Sheet1", sheets[0]))
+	for _, sheet := range sheets {
+		if sheet != sheets[0] {
+			_, err = f.NewSheet(sheet)
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, f.SetSheetRow(sheet, "A1", &header))
+		assert.NoError(t, f.SetSheetRow(sheet, "A2", &row))
+	}
+
+	path := filepath.Join(t.TempDir(), "wilayas.xlsx")
+	assert.NoError(t, f.SaveAs(path))
+	f.Close()
+
+	im := importer.New(nil, nil, nil, mapping, domain.ImportOptions{})
+	for _, sheet := range sheets[:3] {
+		report, err := im.Validate(path, domain.ImportOptions{SheetAsWilaya: true, Sheets: []string{sheet}})
+		assert.NoError(t, err, sheet)
+		assert.Equal(t, 1, report.ValidRows, sheet)
+	}
+
+	// Test: the AG code is the one of the wilaya, whatever the case and accents
+	report, err := im.Validate(path, domain.ImportOptions{SheetAsWilaya: true, Sheets: sheets[:3]})
+	assert.NoError(t, err)
+	for _, column := range report.Columns {
+		if column.Field == "AG" {
+			assert.Equal(t, 6.0, *column.Min, "Béjaïa is wilaya 6")
+			assert.Equal(t, 16.0, *column.Max, "Alger is wilaya 16, not agency 95")
+		}
+	}
+
+	// Test: a name outside the geography gives no AG
+	_, err = im.Validate(path, domain.ImportOptions{SheetAsWilaya: true, Sheets: []string{"Atlantis"}})
+	assert.Error(t, err, "Expected missing AG column")
+}
+
+func TestValidate_NumericSheetNames(t *testing.T) {
+	mapping, err := importer.LoadMapping("../config/column_mapping.json")
+	assert.NoError(t, err, "Failed to load column mapping")
+
+	header := []interface{}{
+		"AVT", "NPens", "EtatPens", "DateNais", "DateJouis", "SexeTP", "NetMens", "TauxD", "TauxRV",
+		"TauxGLB", "AgeAppTP", "DureePension", "AgeMoyenCat", "RisqueAge", "NiveauRisquePredit",
+	}
+	row := []interface{}{
+		"1", "P1", "A", "1950-03-01 00:00:00", "2010-01-01 00:00:00", "M", "15000", "80", "0",
+		"80", "60", "14", "3", "1", "2",
+	}
+
+	sheets := []string{"2023", "123", "16 Alger", "31"}
+	f := excelize.NewFile()
+	assert.NoError(t, f.SetSheetName("Sheet1", sheets[0]))
+	for _, sheet := range sheets {
+		if sheet != sheets[0] {
+			_, err = f.NewSheet(sheet)
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, f.SetSheetRow(sheet, "A1", &header))
+		assert.NoError(t, f.SetSheetRow(sheet, "A2", &row))
+	}
+
+	path := filepath.Join(t.TempDir(), "numeric.xlsx")
+	assert.NoError(t, f.SaveAs(path))
+	f.Close()
+
+	im := importer.New(nil, nil, nil, mapping, domain.ImportOptions{})
+
+	// Test: a number longer than a code is not split into a code and a name
+	for _, sheet := range sheets[:2] {
+		_, err := im.Validate(path, domain.ImportOptions{SheetAsWilaya: true, Sheets: []string{sheet}})
+		assert.Error(t, err, "%s should not give an AG", sheet)
+	}
+
+	// Test: a code followed by a space or alone is still a code
+	for sheet, ag := range map[string]float64{"16 Alger": 16, "31": 31} {
+		report, err := im.Validate(path, domain.ImportOptions{SheetAsWilaya: true, Sheets: []string{sheet}})
+		if assert.NoError(t, err, sheet) {
+			for _, column := range report.Columns {
+				if column.Field == "AG" {
+					assert.Equal(t, ag, *column.Min, sheet)
+				}
+			}
+		}
+	}
+}
+
+func TestValidate_UnknownAgency(t *testing.T) {
+	mapping, err := importer.LoadMapping("../config/column_mapping.json")
+	assert.NoError(t, err, "Failed to load column mapping")
+
+	content := "AG;AVT;NPens;EtatPens;DateNais;DateJouis;SexeTP;NetMens;TauxD;TauxRV;TauxGLB;AgeAppTP;DureePension;AgeMoyenCat;RisqueAge;NiveauRisquePredit\n" +
+		"95;1;P1;A;1950-03-01;2010-01-01;M;15000;80;0;80;60;14;3;1;2\n" +
+		"99;1;P2;A;1950-03-01;2010-01-01;M;15000;80;0;80;60;14;3;1;2\n"
+	path := filepath.Join(t.TempDir(), "agencies.csv")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	// Test: AG codes are checked against the default geography
+	report, err := importer.New(nil, nil, nil, mapping, domain.ImportOptions{}).Validate(path, domain.ImportOptions{Delimiter: ";"})
+	assert.NoError(t, err, "Failed to validate file")
+	assert.Equal(t, 1, report.ValidRows)
+	assert.Equal(t, 1, report.InvalidRows)
+	assert.Equal(t, 3, report.Samples[0].Row)
+	assert.Equal(t, "AG", report.Samples[0].Column)
+	assert.Contains(t, report.Samples[0].Reason, "unknown agency code 99")
+}
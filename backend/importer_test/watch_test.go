@@ -0,0 +1,44 @@
+package importer_test
+
+import (
+	"cnr-tp/domain"
+	"cnr-tp/importer"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcher_MovesFailedFile(t *testing.T) {
+	mapping, err := importer.LoadMapping("../config/column_mapping.json")
+	assert.NoError(t, err, "Failed to load column mapping")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "extract.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("AG;AVT\n16;1\n"), 0o644))
+
+	w := importer.NewWatcher(importer.New(nil, nil, nil, mapping, domain.ImportOptions{}), dir, time.Second)
+
+	// Test: a new file is left alone until it is unchanged for a full poll
+	w.Scan()
+	_, err = os.Stat(path)
+	assert.NoError(t, err, "File should wait for the next poll")
+
+	w.Scan()
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "File should be moved out of the watched directory")
+	_, err = os.Stat(filepath.Join(dir, "failed", "extract.csv"))
+	assert.NoError(t, err, "File should be moved to failed/")
+
+	data, err := os.ReadFile(filepath.Join(dir, "failed", "extract.csv.json"))
+	assert.NoError(t, err, "Sidecar summary should be written")
+
+	var result importer.WatchResult
+	assert.NoError(t, json.Unmarshal(data, &result))
+	assert.Equal(t, domain.ImportStatusFailed, result.Status)
+	assert.Contains(t, result.Error, "missing required column")
+	assert.NotEmpty(t, result.SHA256)
+}
@@ -52,8 +52,11 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Set user ID in the context
+			// Set user ID and role in the context
 			c.Set("userID", uint(claims["user_id"].(float64)))
+			if role, ok := claims["role"].(string); ok {
+				c.Set("role", role)
+			}
 			c.Next()
 		} else {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
@@ -62,3 +65,16 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 	}
 }
+
+// AdminOnly rejects users without the admin role. It must run after
+// AuthMiddleware.
+func AdminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin role required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"cnr-tp/domain"
+	"fmt"
+	"strconv"
+)
+
+// avantageCategorySQL gives the avantage category of a record: AvantageEmpty
+// without an AVT code, and an empty string for codes no category holds. It
+// needs the avantage_codes join.
+const avantageCategorySQL = "CASE WHEN avt IN ('', '0') THEN '" + domain.AvantageEmpty + "' ELSE COALESCE(avantage_codes.category, '') END"
+
+// dimension describes how records are grouped on a domain.Dimension
+type dimension struct {
+	// column is the SQL expression grouped on, and label the expression of
+	// its display label, read from the joined reference tables
+	column string
+	label  string
+	joins  []string
+	// fallback labels the values the reference tables do not
+	fallback func(key string) string
+}
+
+var dimensions = map[domain.Dimension]dimension{
+	domain.DimensionAgency: {
+		column:   "ag",
+		label:    "agencies.name",
+		joins:    []string{"LEFT JOIN agencies ON agencies.code = ag"},
+		fallback: func(key string) string { return "AG " + key },
+	},
+	domain.DimensionEtatPens: {
+		column:   "etat_pens",
+		label:    "etat_pens",
+		fallback: func(key string) string { return key },
+	},
+	domain.DimensionAvantage: {
+		column: avantageCategorySQL,
+		label:  "avantage_categories.label",
+		joins: []string{
+			"LEFT JOIN avantage_codes ON avantage_codes.code = avt",
+			"LEFT JOIN avantage_categories ON avantage_categories.name = avantage_codes.category",
+		},
+		fallback: func(key string) string {
+			if key == domain.AvantageEmpty {
+				return key
+			}
+			return "Autres"
+		},
+	},
+	domain.DimensionSexe: {
+		column:   "sexe_tp",
+		label:    "''",
+		fallback: domain.SexeLabel,
+	},
+	domain.DimensionAVT: {
+		column:   "avt",
+		label:    "avt",
+		fallback: func(key string) string { return key },
+	},
+	domain.DimensionBirthYearBand: {
+		column: "FLOOR(YEAR(date_nais) / 10) * 10",
+		label:  "''",
+		fallback: func(key string) string {
+			decade, err := strconv.Atoi(key)
+			if err != nil {
+				return key
+			}
+			return fmt.Sprintf("%d-%d", decade, decade+9)
+		},
+	},
+	domain.DimensionJouissanceYear: {
+		column:   "YEAR(date_jouis)",
+		label:    "''",
+		fallback: func(key string) string { return key },
+	},
+	domain.DimensionRiskLevel: {
+		column:   "niveau_risque_predit",
+		label:    "risk_levels.label",
+		joins:    []string{"LEFT JOIN risk_levels ON risk_levels.level = niveau_risque_predit"},
+		fallback: func(key string) string { return "Unknown Risk" },
+	},
+}
+
+// labelOf returns label, or the fallback label of key when the reference
+// tables had none
+func (d dimension) labelOf(key, label string) string {
+	if label != "" {
+		return label
+	}
+	return d.fallback(key)
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"cnr-tp/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type importBatchRepository struct {
+	db *gorm.DB
+}
+
+func NewImportBatchRepository(db *gorm.DB) domain.ImportBatchRepository {
+	return &importBatchRepository{db: db}
+}
+
+func (r *importBatchRepository) Create(batch *domain.ImportBatch) error {
+	return r.db.Create(batch).Error
+}
+
+func (r *importBatchRepository) Update(batch *domain.ImportBatch) error {
+	return r.db.Save(batch).Error
+}
+
+func (r *importBatchRepository) FindByID(id uint) (*domain.ImportBatch, error) {
+	var batch domain.ImportBatch
+	err := r.db.First(&batch, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (r *importBatchRepository) FindAll() ([]domain.ImportBatch, error) {
+	var batches []domain.ImportBatch
+	err := r.db.Order("id DESC").Find(&batches).Error
+	return batches, err
+}
+
+func (r *importBatchRepository) Rollback(batch *domain.ImportBatch) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("import_batch_id = ?", batch.ID).Delete(&domain.PensionData{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		now := time.Now()
+		batch.Status = domain.ImportStatusRolledBack
+		batch.RolledBackAt = &now
+		batch.RowsDeleted = int(result.RowsAffected)
+		return tx.Save(batch).Error
+	})
+}
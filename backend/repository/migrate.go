@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"cnr-tp/domain"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// AutoMigrate creates or updates the tables of every persisted model, and
+// seeds the reference tables on first run
+func AutoMigrate(db *gorm.DB) error {
+	if err := dedupePensions(db); err != nil {
+		return err
+	}
+
+	err := db.AutoMigrate(
+		&domain.User{},
+		&domain.PensionData{},
+		&domain.ImportBatch{},
+		&domain.AvantageCategory{},
+		&domain.AvantageCode{},
+		&domain.PensionCategory{},
+		&domain.PensionCategoryCode{},
+		&domain.Wilaya{},
+		&domain.Agency{},
+		&domain.Commune{},
+		&domain.RiskLevel{},
+	)
+	if err != nil {
+		return err
+	}
+
+	// The unique index replaces the natural key index, which allowed duplicates
+	if db.Migrator().HasIndex(&domain.PensionData{}, "idx_pension_natural_key") {
+		if err := db.Migrator().DropIndex(&domain.PensionData{}, "idx_pension_natural_key"); err != nil {
+			return err
+		}
+	}
+	return seedReferenceData(db)
+}
+
+// dedupePensions removes duplicate natural keys before the unique index on
+// them is built, keeping the most recently inserted record of each (NPens, AG)
+func dedupePensions(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&domain.PensionData{}) || migrator.HasIndex(&domain.PensionData{}, "uniq_pension_natural_key") {
+		return nil
+	}
+
+	result := db.Exec(`DELETE p FROM pension_data p
+		JOIN pension_data newer ON newer.n_pens = p.n_pens AND newer.ag = p.ag AND newer.id > p.id`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Removed %d duplicate pension records before building the natural key index", result.RowsAffected)
+	}
+	return nil
+}
@@ -2,8 +2,12 @@ package repository
 
 import (
 	"cnr-tp/domain"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type pensionRepository struct {
@@ -18,6 +22,103 @@ func (r *pensionRepository) Create(pension *domain.PensionData) error {
 	return r.db.Create(pension).Error
 }
 
+// CreateBatch inserts pensions using multi-row INSERT statements of batchSize rows
+func (r *pensionRepository) CreateBatch(pensions []domain.PensionData, batchSize int) error {
+	return r.db.CreateInBatches(pensions, batchSize).Error
+}
+
+// UpsertBatch matches pensions against existing rows on the natural key.
+// New keys are inserted, changed records updated and identical ones skipped.
+// A key repeated within the batch is inserted or updated once with its last
+// row, and its earlier rows count as unchanged.
+//
+// Inserts go through ON DUPLICATE KEY UPDATE on the unique (NPens, AG) index,
+// so a record inserted meanwhile by a concurrent import is updated rather
+// than duplicated. It is still counted as inserted.
+func (r *pensionRepository) UpsertBatch(pensions []domain.PensionData, key domain.UpsertKey, batchSize int) (domain.UpsertResult, error) {
+	var result domain.UpsertResult
+	if len(pensions) == 0 {
+		return result, nil
+	}
+
+	npens := make([]string, 0, len(pensions))
+	for _, p := range pensions {
+		npens = append(npens, p.NPens)
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing []domain.PensionData
+		if err := tx.Where("n_pens IN (?)", npens).Order("id").Find(&existing).Error; err != nil {
+			return err
+		}
+
+		current := make(map[string]domain.PensionData, len(existing))
+		for _, p := range existing {
+			k := naturalKey(p, key)
+			if _, ok := current[k]; !ok {
+				current[k] = p
+			}
+		}
+
+		var inserts []domain.PensionData
+		pending := make(map[string]int)
+
+		for _, p := range pensions {
+			k := naturalKey(p, key)
+
+			// Same new key seen earlier in this batch: the last row wins, and
+			// the record is still inserted only once
+			if i, ok := pending[k]; ok {
+				inserts[i] = p
+				result.Unchanged++
+				continue
+			}
+
+			old, ok := current[k]
+			if !ok {
+				pending[k] = len(inserts)
+				inserts = append(inserts, p)
+				continue
+			}
+
+			// The record keeps the provenance of the batch that created it
+			p.ID = old.ID
+			p.ImportBatchID, p.SourceSheet, p.SourceRow = old.ImportBatchID, old.SourceSheet, old.SourceRow
+			if samePensionData(old, p) {
+				result.Unchanged++
+				continue
+			}
+			if err := tx.Save(&p).Error; err != nil {
+				return err
+			}
+			current[k] = p
+			result.Updated++
+		}
+
+		if len(inserts) > 0 {
+			upsert := clause.OnConflict{DoUpdates: clause.AssignmentColumns(pensionDataColumns)}
+			if err := tx.Clauses(upsert).CreateInBatches(inserts, batchSize).Error; err != nil {
+				return err
+			}
+		}
+		result.Inserted = len(inserts)
+		return nil
+	})
+	if err != nil {
+		return domain.UpsertResult{}, err
+	}
+
+	return result, nil
+}
+
+// pensionDataColumns are the columns holding the data of a record, without
+// its ID and import provenance
+var pensionDataColumns = []string{
+	"ag", "avt", "n_pens", "etat_pens", "date_nais", "date_jouis", "sexe_tp", "net_mens",
+	"taux_d", "taux_rv", "taux_glb", "age_app_tp", "duree_pension", "age_moyen_cat",
+	"risque_age", "niveau_risque_predit", "wilaya",
+}
+
 func (r *pensionRepository) FindByID(id uint) (*domain.PensionData, error) {
 	var pension domain.PensionData
 	err := r.db.First(&pension, id).Error
@@ -27,20 +128,38 @@ func (r *pensionRepository) FindByID(id uint) (*domain.PensionData, error) {
 	return &pension, nil
 }
 
-func (r *pensionRepository) FindAll(avantages []string) ([]domain.PensionData, int64, error) {
+// FindAll returns one page of the records matching the query, with the total
+// number of matching records
+func (r *pensionRepository) FindAll(query domain.PensionQuery) ([]domain.PensionData, int64, error) {
 	var pensions []domain.PensionData
 	var total int64
 
-	db := r.db.Model(&domain.PensionData{})
-	if len(avantages) > 0 {
-		db = db.Where("avt IN (?)", avantages)
-	}
+	db := filterPensions(r.db.Model(&domain.PensionData{}), query.Filter)
 
 	err := db.Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
+	if len(query.Fields) > 0 {
+		columns := make([]string, 0, len(query.Fields))
+		for _, field := range query.Fields {
+			columns = append(columns, domain.PensionColumns[field])
+		}
+		db = db.Select(columns)
+	}
+
+	// id breaks ties so that pages do not overlap
+	for _, sort := range query.Sort {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: domain.PensionColumns[sort.Field]}, Desc: sort.Desc})
+	}
+	db = db.Order("id")
+
+	if query.Limit > 0 {
+		page := max(query.Page, 1)
+		db = db.Offset((page - 1) * query.Limit).Limit(query.Limit)
+	}
+
 	err = db.Find(&pensions).Error
 	if err != nil {
 		return nil, 0, err
@@ -57,44 +176,11 @@ func (r *pensionRepository) Delete(id uint) error {
 	return r.db.Delete(&domain.PensionData{}, id).Error
 }
 
-func (r *pensionRepository) GetRiskLevelStats(wilaya string, categories []string, avantages []string) ([]domain.RiskLevelStats, error) {
+func (r *pensionRepository) GetRiskLevelStats(filter domain.PensionFilter) ([]domain.RiskLevelStats, error) {
 	var stats []domain.RiskLevelStats
 	var total int64
 
-	db := r.db.Model(&domain.PensionData{})
-
-	if wilaya != "" {
-		db = db.Where("ag = ?", wilaya)
-	}
-
-	if len(categories) > 0 {
-		db = db.Where("etat_pens IN (?)", categories)
-	}
-
-	if len(avantages) > 0 {
-		var avtCodes []string
-		includeEmpty := false
-		for _, avt := range avantages {
-			switch avt {
-			case "direct":
-				avtCodes = append(avtCodes, "1", "7", "W", "Z", "4", "9", "G", "5")
-			case "fille majeur":
-				avtCodes = append(avtCodes, "H", "D", "Y")
-			case "Veuves":
-				avtCodes = append(avtCodes, "3", "2", "F", "E", "8", "J")
-			case "(Vide)":
-				includeEmpty = true
-			}
-		}
-
-		if includeEmpty && len(avtCodes) > 0 {
-			db = db.Where("avt IN (?) OR avt = 0", avtCodes)
-		} else if includeEmpty {
-			db = db.Where("avt = 0")
-		} else if len(avtCodes) > 0 {
-			db = db.Where("avt IN (?)", avtCodes)
-		}
-	}
+	db := filterPensions(r.db.Model(&domain.PensionData{}), filter)
 
 	// Get total count for percentage calculation
 	err := db.Count(&total).Error
@@ -106,13 +192,17 @@ func (r *pensionRepository) GetRiskLevelStats(wilaya string, categories []string
 		return []domain.RiskLevelStats{}, nil
 	}
 
-	// Group by niveau_risque_predit and calculate counts
+	// Group by niveau_risque_predit and calculate counts, with the label of
+	// each level from the reference table
 	var results []struct {
-		NiveauRisquePredit int8  `gorm:"column:niveau_risque_predit"`
-		Count              int64 `gorm:"column:count"`
+		NiveauRisquePredit int8   `gorm:"column:niveau_risque_predit"`
+		Label              string `gorm:"column:label"`
+		Count              int64  `gorm:"column:count"`
 	}
 
-	db = db.Select("niveau_risque_predit, count(*) as count").Group("niveau_risque_predit")
+	db = db.Select("niveau_risque_predit, MAX(risk_levels.label) as label, count(*) as count").
+		Joins("LEFT JOIN risk_levels ON risk_levels.level = niveau_risque_predit").
+		Group("niveau_risque_predit")
 	err = db.Find(&results).Error
 	if err != nil {
 		return nil, err
@@ -120,14 +210,9 @@ func (r *pensionRepository) GetRiskLevelStats(wilaya string, categories []string
 
 	// Map numerical risk levels to string labels and calculate percentages
 	for _, res := range results {
-		riskLevel := "Unknown Risk"
-		switch res.NiveauRisquePredit {
-		case 0:
-			riskLevel = "Bas risque"
-		case 1:
-			riskLevel = "Moyen risque"
-		case 2:
-			riskLevel = "Haut risque"
+		riskLevel := res.Label
+		if riskLevel == "" {
+			riskLevel = "Unknown Risk"
 		}
 
 		percentage := (float64(res.Count) / float64(total)) * 100
@@ -140,3 +225,335 @@ func (r *pensionRepository) GetRiskLevelStats(wilaya string, categories []string
 
 	return stats, nil
 }
+
+// GetWilayaStats counts the records of each agency matching the filters,
+// named after the wilaya of the agency
+func (r *pensionRepository) GetWilayaStats(filter domain.PensionFilter) ([]domain.WilayaStats, error) {
+	var results []struct {
+		AG     int8   `gorm:"column:ag"`
+		Wilaya string `gorm:"column:wilaya"`
+		Count  int64  `gorm:"column:count"`
+	}
+
+	err := filterPensions(r.db.Model(&domain.PensionData{}), filter).
+		Select("ag, COALESCE(MAX(wilayas.name), MAX(wilaya)) as wilaya, count(*) as count").
+		Joins("LEFT JOIN agencies ON agencies.code = ag").
+		Joins("LEFT JOIN wilayas ON wilayas.code = agencies.wilaya_code").
+		Group("ag").
+		Order("ag").
+		Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, res := range results {
+		total += res.Count
+	}
+
+	stats := make([]domain.WilayaStats, 0, len(results))
+	for _, res := range results {
+		stats = append(stats, domain.WilayaStats{
+			AG:         res.AG,
+			Wilaya:     res.Wilaya,
+			Count:      int(res.Count),
+			Percentage: (float64(res.Count) / float64(total)) * 100,
+		})
+	}
+
+	return stats, nil
+}
+
+// demographicGroup is one row of a demographic breakdown query
+type demographicGroup struct {
+	Key   string `gorm:"column:group_key"`
+	Label string `gorm:"column:label"`
+	Count int64  `gorm:"column:count"`
+}
+
+// GetDemographics breaks the records matching the filter down by sex, age
+// band and avantage category, one grouped query each
+func (r *pensionRepository) GetDemographics(filter domain.PensionFilter) (*domain.Demographics, error) {
+	demographics := &domain.Demographics{
+		Sexe:      []domain.DemographicGroup{},
+		AgeBands:  []domain.DemographicGroup{},
+		Avantages: []domain.DemographicGroup{},
+	}
+
+	var total int64
+	if err := filterPensions(r.db.Model(&domain.PensionData{}), filter).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	demographics.Total = int(total)
+	if total == 0 {
+		return demographics, nil
+	}
+
+	group := func(key, label string, count int64) domain.DemographicGroup {
+		return domain.DemographicGroup{
+			Key:        key,
+			Label:      label,
+			Count:      int(count),
+			Percentage: (float64(count) / float64(total)) * 100,
+		}
+	}
+
+	var results []demographicGroup
+	err := filterPensions(r.db.Model(&domain.PensionData{}), filter).
+		Select("sexe_tp AS group_key, count(*) AS count").
+		Group("sexe_tp").
+		Order("sexe_tp").
+		Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range results {
+		demographics.Sexe = append(demographics.Sexe, group(res.Key, domain.SexeLabel(res.Key), res.Count))
+	}
+
+	results = nil
+	err = filterPensions(r.db.Model(&domain.PensionData{}), filter).
+		Select(ageBandSQL() + " AS group_key, count(*) AS count").
+		Group("group_key").
+		Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	// Every band is listed in age order, empty ones included
+	counts := make(map[string]int64, len(results))
+	for _, res := range results {
+		counts[res.Key] = res.Count
+	}
+	for _, band := range domain.AgeBands {
+		demographics.AgeBands = append(demographics.AgeBands, group(band.Key, band.Key, counts[band.Key]))
+	}
+	if count := counts[domain.AgeBandUnknown]; count > 0 {
+		demographics.AgeBands = append(demographics.AgeBands, group(domain.AgeBandUnknown, "Inconnu", count))
+	}
+
+	// Codes without a category are grouped under an empty key
+	results = nil
+	err = filterPensions(r.db.Model(&domain.PensionData{}), filter).
+		Select(avantageCategorySQL + " AS group_key, MAX(avantage_categories.label) AS label, count(*) AS count").
+		Joins("LEFT JOIN avantage_codes ON avantage_codes.code = avt").
+		Joins("LEFT JOIN avantage_categories ON avantage_categories.name = avantage_codes.category").
+		Group("group_key").
+		Order("group_key").
+		Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range results {
+		label := res.Label
+		switch {
+		case res.Key == domain.AvantageEmpty:
+			label = domain.AvantageEmpty
+		case res.Key == "":
+			label = "Autres"
+		}
+		demographics.Avantages = append(demographics.Avantages, group(res.Key, label, res.Count))
+	}
+
+	return demographics, nil
+}
+
+// GetCrossTab counts the records matching the filter for every pair of
+// values of the row and column dimensions, ordered by row then column
+func (r *pensionRepository) GetCrossTab(filter domain.PensionFilter, rows, columns domain.Dimension) ([]domain.CrossTabCount, error) {
+	rowDim, ok := dimensions[rows]
+	if !ok {
+		return nil, fmt.Errorf("unknown dimension %q", rows)
+	}
+	colDim, ok := dimensions[columns]
+	if !ok {
+		return nil, fmt.Errorf("unknown dimension %q", columns)
+	}
+
+	db := filterPensions(r.db.Model(&domain.PensionData{}), filter).
+		Select(rowDim.column + " AS row_key, MAX(" + rowDim.label + ") AS row_label, " +
+			colDim.column + " AS column_key, MAX(" + colDim.label + ") AS column_label, count(*) AS count")
+	for _, join := range append(append([]string(nil), rowDim.joins...), colDim.joins...) {
+		db = db.Joins(join)
+	}
+
+	var results []struct {
+		RowKey      string `gorm:"column:row_key"`
+		RowLabel    string `gorm:"column:row_label"`
+		ColumnKey   string `gorm:"column:column_key"`
+		ColumnLabel string `gorm:"column:column_label"`
+		Count       int64  `gorm:"column:count"`
+	}
+	if err := db.Group("row_key, column_key").Order("row_key, column_key").Find(&results).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make([]domain.CrossTabCount, 0, len(results))
+	for _, res := range results {
+		counts = append(counts, domain.CrossTabCount{
+			RowKey:      res.RowKey,
+			RowLabel:    rowDim.labelOf(res.RowKey, res.RowLabel),
+			ColumnKey:   res.ColumnKey,
+			ColumnLabel: colDim.labelOf(res.ColumnKey, res.ColumnLabel),
+			Count:       int(res.Count),
+		})
+	}
+	return counts, nil
+}
+
+// ageBandSQL returns the expression giving the key of the age band of a
+// record, computed from its birth date on the current day
+func ageBandSQL() string {
+	age := "TIMESTAMPDIFF(YEAR, date_nais, CURDATE())"
+
+	var b strings.Builder
+	b.WriteString("CASE WHEN date_nais IS NULL OR date_nais < '1800-01-01' THEN '" + domain.AgeBandUnknown + "'")
+	for _, band := range domain.AgeBands {
+		if band.To == 0 {
+			b.WriteString(" ELSE '" + band.Key + "'")
+			break
+		}
+		b.WriteString(" WHEN " + age + " < " + strconv.Itoa(band.To) + " THEN '" + band.Key + "'")
+	}
+	b.WriteString(" END")
+	return b.String()
+}
+
+// ForEach streams the records matching the filters in id order
+func (r *pensionRepository) ForEach(filter domain.PensionFilter, batchSize int, fn func(pension *domain.PensionData) error) error {
+	var batch []domain.PensionData
+	return filterPensions(r.db, filter).
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				if err := fn(&batch[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// filterPensions applies the conditions of a pension filter
+func filterPensions(db *gorm.DB, filter domain.PensionFilter) *gorm.DB {
+	// Wilayas and agencies are given by code or by name, and resolved to AG
+	// codes through the geography tables
+	if wilaya := strings.TrimSpace(filter.Wilaya); wilaya != "" {
+		agencies := newQuery(db).Model(&domain.Agency{}).Select("code")
+		if code, err := strconv.Atoi(wilaya); err == nil {
+			agencies = agencies.Where("wilaya_code = ?", code)
+		} else {
+			agencies = agencies.Where("wilaya_code IN (?)", newQuery(db).Model(&domain.Wilaya{}).Select("code").Where("name = ?", wilaya))
+		}
+		db = db.Where("ag IN (?)", agencies)
+	}
+	if agency := strings.TrimSpace(filter.Agency); agency != "" {
+		if code, err := strconv.Atoi(agency); err == nil {
+			db = db.Where("ag = ?", code)
+		} else {
+			db = db.Where("ag IN (?)", newQuery(db).Model(&domain.Agency{}).Select("code").Where("name = ?", agency))
+		}
+	}
+
+	// Categories are pension states, or pension category names selecting the
+	// AgeMoyenCat values the reference tables assign to them
+	if len(filter.Categories) > 0 {
+		db = db.Where(newQuery(db).Where("etat_pens IN (?)", filter.Categories).
+			Or("age_moyen_cat IN (?)", newQuery(db).Model(&domain.PensionCategoryCode{}).Select("age_moyen_cat").Where("category IN (?)", filter.Categories)))
+	}
+
+	// Category names select the codes the reference tables assign to them,
+	// other values are codes
+	if len(filter.Avantages) > 0 {
+		var values []string
+		includeEmpty := false
+		for _, avt := range filter.Avantages {
+			if avt == domain.AvantageEmpty {
+				includeEmpty = true
+				continue
+			}
+			values = append(values, avt)
+		}
+
+		conditions := newQuery(db)
+		if len(values) > 0 {
+			conditions = conditions.Where("avt IN (?)", values).
+				Or("avt IN (?)", newQuery(db).Model(&domain.AvantageCode{}).Select("code").Where("category IN (?)", values))
+		}
+		if includeEmpty {
+			conditions = conditions.Or("avt IN ('', '0')")
+		}
+		db = db.Where(conditions)
+	}
+
+	if len(filter.Sexe) > 0 {
+		db = db.Where("sexe_tp IN (?)", filter.Sexe)
+	}
+
+	if len(filter.RiskLevels) > 0 {
+		db = db.Where("niveau_risque_predit IN (?)", filter.RiskLevels)
+	}
+
+	// Column names come from domain.PensionColumns, never from the request
+	for _, r := range filter.NumericRanges() {
+		column := domain.PensionColumns[r.Field]
+		if r.Range.Min != nil {
+			db = db.Where(column+" >= ?", *r.Range.Min)
+		}
+		if r.Range.Max != nil {
+			db = db.Where(column+" <= ?", *r.Range.Max)
+		}
+	}
+	for _, r := range filter.DateRanges() {
+		column := domain.PensionColumns[r.Field]
+		if r.Range.After != nil {
+			db = db.Where(column+" > ?", r.Range.After.Time)
+		}
+		if r.Range.Before != nil {
+			db = db.Where(column+" < ?", r.Range.Before.Time)
+		}
+	}
+
+	return db
+}
+
+// newQuery starts a statement without the conditions of db, for grouped
+// conditions and subqueries
+func newQuery(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true})
+}
+
+// Transaction runs fn with a repository bound to a single database
+// transaction, committing only if fn returns nil
+func (r *pensionRepository) Transaction(fn func(repo domain.PensionRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&pensionRepository{db: tx})
+	})
+}
+
+func naturalKey(p domain.PensionData, key domain.UpsertKey) string {
+	if key == domain.UpsertKeyNPensAG {
+		return p.NPens + "|" + strconv.Itoa(int(p.AG))
+	}
+	return p.NPens
+}
+
+// samePensionData reports whether two records hold the same data, ignoring the
+// ID and import provenance
+func samePensionData(a, b domain.PensionData) bool {
+	return a.AG == b.AG &&
+		a.AVT == b.AVT &&
+		a.NPens == b.NPens &&
+		a.EtatPens == b.EtatPens &&
+		a.DateNais.Equal(b.DateNais) &&
+		a.DateJouis.Equal(b.DateJouis) &&
+		a.SexeTP == b.SexeTP &&
+		a.NetMens == b.NetMens &&
+		a.TauxD == b.TauxD &&
+		a.TauxRV == b.TauxRV &&
+		a.TauxGLB == b.TauxGLB &&
+		a.AgeAppTP == b.AgeAppTP &&
+		a.DureePension == b.DureePension &&
+		a.AgeMoyenCat == b.AgeMoyenCat &&
+		a.RisqueAge == b.RisqueAge &&
+		a.NiveauRisquePredit == b.NiveauRisquePredit &&
+		a.Wilaya == b.Wilaya
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"cnr-tp/domain"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Pivot groups the records matching the query filter on its dimensions and
+// computes its measures for every group. The SQL is built from the dimension
+// and measure whitelists only: dimensions come from the dimensions table and
+// measured columns from domain.PensionColumns.
+//
+// Records are first selected with their dimension keys, and with their rank
+// and group size when percentiles are asked for; the outer query groups them.
+// Percentiles use the nearest-rank method.
+func (r *pensionRepository) Pivot(query domain.PivotQuery) (*domain.PivotResult, error) {
+	dims := make([]dimension, 0, len(query.Dimensions))
+	keys := make([]string, 0, len(query.Dimensions))
+	for i, name := range query.Dimensions {
+		dim, ok := dimensions[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown dimension %q", name)
+		}
+		dims = append(dims, dim)
+		keys = append(keys, fmt.Sprintf("d%d", i))
+	}
+
+	// Window of the group of a record, for ranks and group sizes
+	partition := ""
+	if len(dims) > 0 {
+		columns := make([]string, 0, len(dims))
+		for _, dim := range dims {
+			columns = append(columns, dim.column)
+		}
+		partition = "PARTITION BY " + strings.Join(columns, ", ")
+	}
+
+	var inner, outer []string
+	for i, dim := range dims {
+		inner = append(inner, fmt.Sprintf("%s AS d%d, %s AS l%d", dim.column, i, dim.label, i))
+		outer = append(outer, fmt.Sprintf("d%d, MAX(l%d) AS l%d", i, i, i))
+	}
+
+	selected := make(map[string]bool)
+	for _, m := range query.Measures {
+		column := ""
+		if m.Func != domain.MeasureCount {
+			var ok bool
+			if column, ok = measureColumn(m.Field); !ok {
+				return nil, fmt.Errorf("cannot measure field %q", m.Field)
+			}
+			if !selected[column] {
+				selected[column] = true
+				inner = append(inner, column)
+			}
+		}
+
+		var expr string
+		switch m.Func {
+		case domain.MeasureCount:
+			expr = "COUNT(*)"
+		case domain.MeasureSum, domain.MeasureAvg, domain.MeasureMin, domain.MeasureMax:
+			expr = fmt.Sprintf("%s(%s)", strings.ToUpper(string(m.Func)), column)
+		case domain.MeasurePercentile:
+			if m.Percentile < 1 || m.Percentile > 99 {
+				return nil, fmt.Errorf("invalid percentile %d", m.Percentile)
+			}
+			if !selected["group_size"] {
+				selected["group_size"] = true
+				inner = append(inner, "COUNT(*) OVER ("+partition+") AS group_size")
+			}
+			rank := "rank_" + column
+			if !selected[rank] {
+				selected[rank] = true
+				window := strings.TrimSpace(partition + " ORDER BY " + column)
+				inner = append(inner, fmt.Sprintf("ROW_NUMBER() OVER (%s) AS %s", window, rank))
+			}
+			expr = fmt.Sprintf("MIN(CASE WHEN %s >= CEIL(%d * group_size / 100) THEN %s END)", rank, m.Percentile, column)
+		default:
+			return nil, fmt.Errorf("unknown measure function %q", m.Func)
+		}
+		outer = append(outer, expr+" AS "+m.Name())
+	}
+	if len(inner) == 0 {
+		// A count without dimensions needs no column
+		inner = append(inner, "1 AS one")
+	}
+
+	records := filterPensions(r.db.Model(&domain.PensionData{}), query.Filter).Select(strings.Join(inner, ", "))
+	joined := make(map[string]bool)
+	for _, dim := range dims {
+		for _, join := range dim.joins {
+			if !joined[join] {
+				joined[join] = true
+				records = records.Joins(join)
+			}
+		}
+	}
+
+	db := newQuery(r.db).Table("(?) AS records", records).Select(strings.Join(outer, ", "))
+	if len(keys) > 0 {
+		db = db.Group(strings.Join(keys, ", ")).Order(strings.Join(keys, ", "))
+	}
+
+	rows, err := db.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanPivot(rows, query, dims)
+}
+
+// measureColumn returns the column of a field measures accept
+func measureColumn(field string) (string, bool) {
+	for _, f := range domain.MeasureFields {
+		if f == field {
+			return domain.PensionColumns[field], true
+		}
+	}
+	return "", false
+}
+
+// scanPivot reads the rows of a pivot query: the key and label of each
+// dimension, then each measure
+func scanPivot(rows *sql.Rows, query domain.PivotQuery, dims []dimension) (*domain.PivotResult, error) {
+	result := &domain.PivotResult{Rows: [][]interface{}{}}
+	for _, name := range query.Dimensions {
+		result.Columns = append(result.Columns,
+			domain.PivotColumn{Name: string(name), Kind: domain.PivotColumnDimension},
+			domain.PivotColumn{Name: string(name) + "_label", Kind: domain.PivotColumnLabel})
+	}
+	for _, m := range query.Measures {
+		result.Columns = append(result.Columns, domain.PivotColumn{Name: m.Name(), Kind: domain.PivotColumnMeasure})
+	}
+
+	for rows.Next() {
+		keys := make([]sql.NullString, 2*len(dims))
+		values := make([]sql.NullFloat64, len(query.Measures))
+		dest := make([]interface{}, 0, len(keys)+len(values))
+		for i := range keys {
+			dest = append(dest, &keys[i])
+		}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		row := make([]interface{}, 0, len(dest))
+		for i, dim := range dims {
+			key, label := keys[2*i], keys[2*i+1]
+			if !key.Valid {
+				row = append(row, nil, nil)
+				continue
+			}
+			row = append(row, key.String, dim.labelOf(key.String, label.String))
+		}
+		for i, m := range query.Measures {
+			switch {
+			case !values[i].Valid:
+				row = append(row, nil)
+			case m.Func == domain.MeasureCount:
+				row = append(row, int64(values[i].Float64))
+			default:
+				row = append(row, values[i].Float64)
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"cnr-tp/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type referenceRepository struct {
+	db *gorm.DB
+}
+
+func NewReferenceRepository(db *gorm.DB) domain.ReferenceRepository {
+	return &referenceRepository{db: db}
+}
+
+func (r *referenceRepository) Get() (*domain.ReferenceData, error) {
+	var data domain.ReferenceData
+
+	if err := r.db.Order("position, name").Find(&data.AvantageCategories).Error; err != nil {
+		return nil, err
+	}
+	var avantageCodes []domain.AvantageCode
+	if err := r.db.Order("code").Find(&avantageCodes).Error; err != nil {
+		return nil, err
+	}
+	for i := range data.AvantageCategories {
+		category := &data.AvantageCategories[i]
+		category.Codes = []string{}
+		for _, code := range avantageCodes {
+			if code.Category == category.Name {
+				category.Codes = append(category.Codes, code.Code)
+			}
+		}
+	}
+
+	if err := r.db.Order("position, name").Find(&data.PensionCategories).Error; err != nil {
+		return nil, err
+	}
+	var pensionCodes []domain.PensionCategoryCode
+	if err := r.db.Order("age_moyen_cat").Find(&pensionCodes).Error; err != nil {
+		return nil, err
+	}
+	for i := range data.PensionCategories {
+		category := &data.PensionCategories[i]
+		category.AgeMoyenCats = []int8{}
+		for _, code := range pensionCodes {
+			if code.Category == category.Name {
+				category.AgeMoyenCats = append(category.AgeMoyenCats, code.AgeMoyenCat)
+			}
+		}
+	}
+
+	if err := r.db.Order("code").Find(&data.Wilayas).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Order("code").Find(&data.Agencies).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Order("code").Find(&data.Communes).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Order("level").Find(&data.RiskLevels).Error; err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// SaveAvantageCategory writes the category and moves its codes to it. Codes
+// no longer listed are dropped.
+func (r *referenceRepository) SaveAvantageCategory(category *domain.AvantageCategory) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(category).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("category = ?", category.Name).Delete(&domain.AvantageCode{}).Error; err != nil {
+			return err
+		}
+		if len(category.Codes) == 0 {
+			return nil
+		}
+
+		codes := make([]domain.AvantageCode, 0, len(category.Codes))
+		for _, code := range category.Codes {
+			codes = append(codes, domain.AvantageCode{Code: code, Category: category.Name})
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&codes).Error
+	})
+}
+
+func (r *referenceRepository) DeleteAvantageCategory(name string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("category = ?", name).Delete(&domain.AvantageCode{}).Error; err != nil {
+			return err
+		}
+		return deleted(tx.Delete(&domain.AvantageCategory{}, "name = ?", name))
+	})
+}
+
+// SavePensionCategory writes the category and moves its AgeMoyenCat values
+// to it. Values no longer listed are dropped.
+func (r *referenceRepository) SavePensionCategory(category *domain.PensionCategory) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(category).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("category = ?", category.Name).Delete(&domain.PensionCategoryCode{}).Error; err != nil {
+			return err
+		}
+		if len(category.AgeMoyenCats) == 0 {
+			return nil
+		}
+
+		codes := make([]domain.PensionCategoryCode, 0, len(category.AgeMoyenCats))
+		for _, value := range category.AgeMoyenCats {
+			codes = append(codes, domain.PensionCategoryCode{AgeMoyenCat: value, Category: category.Name})
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&codes).Error
+	})
+}
+
+func (r *referenceRepository) DeletePensionCategory(name string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("category = ?", name).Delete(&domain.PensionCategoryCode{}).Error; err != nil {
+			return err
+		}
+		return deleted(tx.Delete(&domain.PensionCategory{}, "name = ?", name))
+	})
+}
+
+func (r *referenceRepository) SaveWilaya(wilaya *domain.Wilaya) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(wilaya).Error
+}
+
+func (r *referenceRepository) DeleteWilaya(code int) error {
+	return deleted(r.db.Delete(&domain.Wilaya{}, "code = ?", code))
+}
+
+func (r *referenceRepository) SaveAgency(agency *domain.Agency) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(agency).Error
+}
+
+func (r *referenceRepository) DeleteAgency(code int8) error {
+	return deleted(r.db.Delete(&domain.Agency{}, "code = ?", code))
+}
+
+func (r *referenceRepository) SaveCommune(commune *domain.Commune) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(commune).Error
+}
+
+func (r *referenceRepository) DeleteCommune(code int) error {
+	return deleted(r.db.Delete(&domain.Commune{}, "code = ?", code))
+}
+
+func (r *referenceRepository) SaveRiskLevel(level *domain.RiskLevel) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(level).Error
+}
+
+// deleted reports ErrReferenceNotFound when a delete matched no row
+func deleted(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrReferenceNotFound
+	}
+	return nil
+}
+
+// seedReferenceData fills the reference tables that are still empty with the
+// default code lists, leaving edited tables alone
+func seedReferenceData(db *gorm.DB) error {
+	defaults := domain.DefaultReferenceData()
+	repo := &referenceRepository{db: db}
+
+	var count int64
+	if err := db.Model(&domain.AvantageCategory{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		for i := range defaults.AvantageCategories {
+			if err := repo.SaveAvantageCategory(&defaults.AvantageCategories[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := db.Model(&domain.PensionCategory{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		for i := range defaults.PensionCategories {
+			if err := repo.SavePensionCategory(&defaults.PensionCategories[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := db.Model(&domain.Wilaya{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		if err := db.Create(&defaults.Wilayas).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := db.Model(&domain.Agency{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		if err := db.Create(&defaults.Agencies).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := db.Model(&domain.RiskLevel{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		if err := db.Create(&defaults.RiskLevels).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
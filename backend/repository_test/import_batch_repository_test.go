@@ -0,0 +1,74 @@
+package repository_test
+
+import (
+	"cnr-tp/config"
+	"cnr-tp/domain"
+	"cnr-tp/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// openTestDB connects to the configured MySQL database and migrates it. The
+// test is skipped when no database is configured or reachable.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Skipf("no database configured: %v", err)
+	}
+	db, err := gorm.Open(mysql.Open(cfg.GetDSN()), &gorm.Config{})
+	if err != nil {
+		t.Skipf("database unreachable: %v", err)
+	}
+	require.NoError(t, repository.AutoMigrate(db))
+	return db
+}
+
+func TestImportBatchRepository_Rollback(t *testing.T) {
+	db := openTestDB(t)
+	repo := repository.NewImportBatchRepository(db)
+
+	batch := &domain.ImportBatch{FileName: "rollback_test.xlsx", Status: domain.ImportStatusCompleted, StartedAt: time.Now()}
+	other := &domain.ImportBatch{FileName: "rollback_test_other.xlsx", Status: domain.ImportStatusCompleted, StartedAt: time.Now()}
+	require.NoError(t, repo.Create(batch))
+	require.NoError(t, repo.Create(other))
+	t.Cleanup(func() {
+		db.Where("import_batch_id IN ?", []uint{batch.ID, other.ID}).Delete(&domain.PensionData{})
+		db.Delete(&domain.ImportBatch{}, []uint{batch.ID, other.ID})
+	})
+
+	pensions := []domain.PensionData{
+		{NPens: "RB-TEST-1", AG: 16, ImportBatchID: &batch.ID},
+		{NPens: "RB-TEST-2", AG: 16, ImportBatchID: &batch.ID},
+		{NPens: "RB-TEST-3", AG: 16, ImportBatchID: &batch.ID},
+		{NPens: "RB-TEST-4", AG: 31, ImportBatchID: &other.ID},
+	}
+	require.NoError(t, db.Create(&pensions).Error)
+
+	require.NoError(t, repo.Rollback(batch))
+	assert.Equal(t, domain.ImportStatusRolledBack, batch.Status)
+	assert.NotNil(t, batch.RolledBackAt)
+	assert.Equal(t, 3, batch.RowsDeleted)
+
+	// The stored batch is marked rolled back, and only its records are gone
+	stored, err := repo.FindByID(batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ImportStatusRolledBack, stored.Status)
+	assert.Equal(t, 3, stored.RowsDeleted)
+
+	var remaining int64
+	db.Model(&domain.PensionData{}).Where("import_batch_id = ?", batch.ID).Count(&remaining)
+	assert.Zero(t, remaining)
+	db.Model(&domain.PensionData{}).Where("import_batch_id = ?", other.ID).Count(&remaining)
+	assert.Equal(t, int64(1), remaining)
+
+	// A second rollback finds nothing left to delete
+	require.NoError(t, repo.Rollback(batch))
+	assert.Equal(t, 0, batch.RowsDeleted)
+}
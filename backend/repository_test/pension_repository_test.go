@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -44,7 +45,7 @@ func TestGetRiskLevelStats(t *testing.T) {
 	// }
 
 	// Test case 1: Get all risk level stats (no wilaya filter)
-	stats, err := pensionRepo.GetRiskLevelStats("", []string{}, []string{})
+	stats, err := pensionRepo.GetRiskLevelStats(domain.PensionFilter{})
 	// assert.NoError(t, err)
 	// assert.Len(t, stats, 3)
 
@@ -74,7 +75,7 @@ func TestGetRiskLevelStats(t *testing.T) {
 	assert.InDelta(t, 20.0, hautRisque.Percentage, 0.01)
 
 	// Test case 2: Get risk level stats for a specific wilaya (Algiers)
-	statsAlgiers, err := pensionRepo.GetRiskLevelStats("Algiers", []string{}, []string{})
+	statsAlgiers, err := pensionRepo.GetRiskLevelStats(domain.PensionFilter{Wilaya: "Algiers"})
 	assert.NoError(t, err)
 	assert.Len(t, statsAlgiers, 2)
 
@@ -89,7 +90,7 @@ func TestGetRiskLevelStats(t *testing.T) {
 	assert.InDelta(t, 33.33, hautRisqueAlgiers.Percentage, 0.01)
 
 	// Test case 3: Get risk level stats for a specific wilaya (Oran)
-	statsOran, err := pensionRepo.GetRiskLevelStats("Oran", []string{}, []string{})
+	statsOran, err := pensionRepo.GetRiskLevelStats(domain.PensionFilter{Wilaya: "Oran"})
 	assert.NoError(t, err)
 	assert.Len(t, statsOran, 2)
 
@@ -104,7 +105,75 @@ func TestGetRiskLevelStats(t *testing.T) {
 	assert.InDelta(t, 50.0, hautRisqueOran.Percentage, 0.01)
 
 	// Test case 4: Get risk level stats for a non-existent wilaya
-	statsNonExistent, err := pensionRepo.GetRiskLevelStats("NonExistent", []string{}, []string{})
+	statsNonExistent, err := pensionRepo.GetRiskLevelStats(domain.PensionFilter{Wilaya: "NonExistent"})
 	assert.NoError(t, err)
 	assert.Len(t, statsNonExistent, 0)
 }
+
+func TestUpsertBatch(t *testing.T) {
+	db := openTestDB(t)
+	repo := repository.NewPensionRepository(db)
+
+	cleanup := func() { db.Where("n_pens LIKE ?", "UPS-TEST-%").Delete(&domain.PensionData{}) }
+	cleanup()
+	t.Cleanup(cleanup)
+
+	require.NoError(t, repo.Create(&domain.PensionData{NPens: "UPS-TEST-1", AG: 16, NetMens: 1000}))
+	require.NoError(t, repo.Create(&domain.PensionData{NPens: "UPS-TEST-2", AG: 16, NetMens: 2000}))
+
+	result, err := repo.UpsertBatch([]domain.PensionData{
+		{NPens: "UPS-TEST-1", AG: 16, NetMens: 1000}, // unchanged
+		{NPens: "UPS-TEST-2", AG: 16, NetMens: 2500}, // updated
+		{NPens: "UPS-TEST-3", AG: 16, NetMens: 3000}, // inserted
+		{NPens: "UPS-TEST-3", AG: 16, NetMens: 3500}, // repeated in the batch, the last row wins
+	}, domain.UpsertKeyNPens, 100)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UpsertResult{Inserted: 1, Updated: 1, Unchanged: 2}, result)
+
+	var stored []domain.PensionData
+	require.NoError(t, db.Where("n_pens LIKE ?", "UPS-TEST-%").Order("n_pens").Find(&stored).Error)
+	require.Len(t, stored, 3)
+	assert.Equal(t, 1000.0, stored[0].NetMens)
+	assert.Equal(t, 2500.0, stored[1].NetMens)
+	assert.Equal(t, 3500.0, stored[2].NetMens)
+
+	// The unique natural key rejects a duplicate inserted behind the upsert's back
+	assert.Error(t, repo.Create(&domain.PensionData{NPens: "UPS-TEST-3", AG: 16}))
+
+	// With the NPens+AG key, the same NPens in another agency is a new record
+	result, err = repo.UpsertBatch([]domain.PensionData{
+		{NPens: "UPS-TEST-1", AG: 31, NetMens: 1000},
+	}, domain.UpsertKeyNPensAG, 100)
+	require.NoError(t, err)
+	assert.Equal(t, domain.UpsertResult{Inserted: 1}, result)
+}
+
+func TestAutoMigrate_RemovesDuplicates(t *testing.T) {
+	db := openTestDB(t)
+
+	cleanup := func() { db.Where("n_pens LIKE ?", "DUP-TEST-%").Delete(&domain.PensionData{}) }
+	cleanup()
+	t.Cleanup(cleanup)
+
+	// A table migrated before the unique index still holds duplicates
+	require.NoError(t, db.Migrator().DropIndex(&domain.PensionData{}, "uniq_pension_natural_key"))
+	pensions := []domain.PensionData{
+		{NPens: "DUP-TEST-1", AG: 16, NetMens: 1000},
+		{NPens: "DUP-TEST-1", AG: 16, NetMens: 1500},
+		{NPens: "DUP-TEST-1", AG: 31, NetMens: 2000},
+		{NPens: "DUP-TEST-2", AG: 16, NetMens: 3000},
+	}
+	require.NoError(t, db.Create(&pensions).Error)
+
+	// Test: the migration keeps the latest record of each key and builds the index
+	require.NoError(t, repository.AutoMigrate(db))
+	assert.True(t, db.Migrator().HasIndex(&domain.PensionData{}, "uniq_pension_natural_key"))
+
+	var stored []domain.PensionData
+	require.NoError(t, db.Where("n_pens LIKE ?", "DUP-TEST-%").Order("n_pens, ag").Find(&stored).Error)
+	require.Len(t, stored, 3)
+	assert.Equal(t, pensions[1].ID, stored[0].ID)
+	assert.Equal(t, 1500.0, stored[0].NetMens)
+	assert.Equal(t, pensions[2].ID, stored[1].ID)
+	assert.Equal(t, pensions[3].ID, stored[2].ID)
+}
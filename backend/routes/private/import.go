@@ -0,0 +1,27 @@
+package private
+
+import (
+	"cnr-tp/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+func NewImportRouter(router *gin.RouterGroup, importHandler *api.ImportHandler) {
+	// Import routes
+	router.POST("/imports", importHandler.UploadImport)
+	router.POST("/imports/validate", importHandler.ValidateImport)
+}
+
+// NewImportJobRouter registers the routes following an import job. The
+// handlers restrict each job to its uploader and to admins.
+func NewImportJobRouter(router *gin.RouterGroup, importHandler *api.ImportHandler) {
+	router.GET("/imports/:id", importHandler.GetImport)
+	router.GET("/imports/:id/rejected", importHandler.GetRejectReport)
+}
+
+// NewImportBatchRouter registers the routes listing and rolling back import batches
+func NewImportBatchRouter(router *gin.RouterGroup, importHandler *api.ImportHandler) {
+	router.GET("/import-batches", importHandler.GetImportBatches)
+	router.GET("/import-batches/:id", importHandler.GetImportBatch)
+	router.POST("/import-batches/:id/rollback", importHandler.RollbackImportBatch)
+}
@@ -16,4 +16,19 @@ func NewPensionRouter(router *gin.RouterGroup, pensionHandler *api.PensionHandle
 
 	// Risk stats route
 	router.POST("/pensions/risk-stats", pensionHandler.GetRiskLevelStats)
+	router.GET("/pensions/demographics", pensionHandler.GetDemographics)
+	router.POST("/pensions/demographics", pensionHandler.GetDemographics)
+	router.GET("/pensions/crosstab", pensionHandler.GetCrossTab)
+	router.POST("/pensions/crosstab", pensionHandler.GetCrossTab)
+	router.GET("/pensions/pivot", pensionHandler.Pivot)
+	router.POST("/pensions/pivot", pensionHandler.Pivot)
+}
+
+// NewPensionExportRouter registers the bulk export routes, which can download
+// the whole table
+func NewPensionExportRouter(router *gin.RouterGroup, pensionHandler *api.PensionHandler) {
+	router.GET("/pensions/export.xlsx", pensionHandler.ExportPensionsXLSX)
+	router.GET("/pensions/export.csv", pensionHandler.ExportPensionsCSV)
+	router.GET("/pensions/export.ndjson", pensionHandler.ExportPensionsNDJSON)
+	router.GET("/pensions/risk-report.xlsx", pensionHandler.ExportRiskReport)
 }
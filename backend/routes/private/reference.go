@@ -0,0 +1,27 @@
+package private
+
+import (
+	"cnr-tp/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+func NewReferenceRouter(router *gin.RouterGroup, referenceHandler *api.ReferenceHandler) {
+	// Reference data routes
+	router.GET("/reference", referenceHandler.GetReferenceData)
+}
+
+// NewReferenceAdminRouter registers the routes editing the reference data
+func NewReferenceAdminRouter(router *gin.RouterGroup, referenceHandler *api.ReferenceHandler) {
+	router.PUT("/reference/avantage-categories/:name", referenceHandler.SaveAvantageCategory)
+	router.DELETE("/reference/avantage-categories/:name", referenceHandler.DeleteAvantageCategory)
+	router.PUT("/reference/pension-categories/:name", referenceHandler.SavePensionCategory)
+	router.DELETE("/reference/pension-categories/:name", referenceHandler.DeletePensionCategory)
+	router.PUT("/reference/wilayas/:code", referenceHandler.SaveWilaya)
+	router.DELETE("/reference/wilayas/:code", referenceHandler.DeleteWilaya)
+	router.PUT("/reference/agencies/:code", referenceHandler.SaveAgency)
+	router.DELETE("/reference/agencies/:code", referenceHandler.DeleteAgency)
+	router.PUT("/reference/communes/:code", referenceHandler.SaveCommune)
+	router.DELETE("/reference/communes/:code", referenceHandler.DeleteCommune)
+	router.PUT("/reference/risk-levels/:level", referenceHandler.SaveRiskLevel)
+}
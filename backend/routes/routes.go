@@ -11,7 +11,7 @@ import (
 )
 
 // Setup configures all routes for the application
-func Setup(router *gin.Engine, userHandler *api.UserHandler, pensionHandler *api.PensionHandler) {
+func Setup(router *gin.Engine, userHandler *api.UserHandler, pensionHandler *api.PensionHandler, importHandler *api.ImportHandler, referenceHandler *api.ReferenceHandler) {
 	// Configure CORS
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"*"}
@@ -40,6 +40,7 @@ func Setup(router *gin.Engine, userHandler *api.UserHandler, pensionHandler *api
 		{
 			private.NewUserRouter(userRouter, userHandler)
 			private.NewPensionRouter(userRouter, pensionHandler)
+			private.NewReferenceRouter(userRouter, referenceHandler)
 		}
 
 		// Admin routes with middleware
@@ -48,6 +49,42 @@ func Setup(router *gin.Engine, userHandler *api.UserHandler, pensionHandler *api
 		{
 			private.NewUserRouter(adminRouter, userHandler)
 			private.NewPensionRouter(adminRouter, pensionHandler)
+			private.NewReferenceRouter(adminRouter, referenceHandler)
+		}
+
+		// Imports can overwrite any record, so only admins may start them
+		importRouter := adminRouter.Group("")
+		importRouter.Use(middleware.AuthMiddleware(), middleware.AdminOnly())
+		{
+			private.NewImportRouter(importRouter, importHandler)
+		}
+
+		// Import jobs are followed by their uploader or an admin
+		importJobRouter := adminRouter.Group("")
+		importJobRouter.Use(middleware.AuthMiddleware())
+		{
+			private.NewImportJobRouter(importJobRouter, importHandler)
+		}
+
+		// Import batches can delete records, so only admins may use them
+		importBatchRouter := adminRouter.Group("")
+		importBatchRouter.Use(middleware.AuthMiddleware(), middleware.AdminOnly())
+		{
+			private.NewImportBatchRouter(importBatchRouter, importHandler)
+		}
+
+		// Bulk exports are restricted to admins
+		exportRouter := adminRouter.Group("")
+		exportRouter.Use(middleware.AuthMiddleware(), middleware.AdminOnly())
+		{
+			private.NewPensionExportRouter(exportRouter, pensionHandler)
+		}
+
+		// Reference data is edited by admins only
+		referenceRouter := adminRouter.Group("")
+		referenceRouter.Use(middleware.AuthMiddleware(), middleware.AdminOnly())
+		{
+			private.NewReferenceAdminRouter(referenceRouter, referenceHandler)
 		}
 	}
 }
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"cnr-tp/domain"
+	"sort"
+	"strconv"
+)
+
+// GetCrossTab lays the counts of every pair of dimension values out as a
+// matrix, with zero cells for the missing pairs, row percentages and totals
+func (u *pensionUseCase) GetCrossTab(filter domain.PensionFilter, rows, columns domain.Dimension) (*domain.CrossTab, error) {
+	counts, err := u.pensionRepo.GetCrossTab(filter, rows, columns)
+	if err != nil {
+		return nil, err
+	}
+	return buildCrossTab(rows, columns, counts), nil
+}
+
+// buildCrossTab expects counts ordered by row
+func buildCrossTab(rows, columns domain.Dimension, counts []domain.CrossTabCount) *domain.CrossTab {
+	tab := &domain.CrossTab{
+		RowDimension:    rows,
+		ColumnDimension: columns,
+		Columns:         []domain.CrossTabColumn{},
+		Rows:            []domain.CrossTabRow{},
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range counts {
+		if !seen[c.ColumnKey] {
+			seen[c.ColumnKey] = true
+			tab.Columns = append(tab.Columns, domain.CrossTabColumn{Key: c.ColumnKey, Label: c.ColumnLabel})
+		}
+	}
+	sort.Slice(tab.Columns, func(i, j int) bool { return keyLess(tab.Columns[i].Key, tab.Columns[j].Key) })
+	position := make(map[string]int, len(tab.Columns))
+	for i, column := range tab.Columns {
+		position[column.Key] = i
+	}
+
+	for _, c := range counts {
+		if n := len(tab.Rows); n == 0 || tab.Rows[n-1].Key != c.RowKey {
+			tab.Rows = append(tab.Rows, domain.CrossTabRow{Key: c.RowKey, Label: c.RowLabel, Cells: make([]domain.CrossTabCell, len(tab.Columns))})
+		}
+		row := &tab.Rows[len(tab.Rows)-1]
+		row.Cells[position[c.ColumnKey]].Count += c.Count
+		row.Total += c.Count
+		tab.Columns[position[c.ColumnKey]].Total += c.Count
+		tab.Total += c.Count
+	}
+
+	if tab.Total == 0 {
+		return tab
+	}
+	for i := range tab.Rows {
+		row := &tab.Rows[i]
+		row.Percentage = float64(row.Total) / float64(tab.Total) * 100
+		for j := range row.Cells {
+			row.Cells[j].Percentage = float64(row.Cells[j].Count) / float64(row.Total) * 100
+		}
+	}
+	for i := range tab.Columns {
+		tab.Columns[i].Percentage = float64(tab.Columns[i].Total) / float64(tab.Total) * 100
+	}
+	return tab
+}
+
+// keyLess orders numeric keys by value and other keys as text, numbers first
+func keyLess(a, b string) bool {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return x < y
+	case errA == nil || errB == nil:
+		return errA == nil
+	}
+	return a < b
+}
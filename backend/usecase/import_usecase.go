@@ -0,0 +1,234 @@
+package usecase
+
+import (
+	"cnr-tp/domain"
+	"cnr-tp/importer"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type importUseCase struct {
+	importer  *importer.Importer
+	batchRepo domain.ImportBatchRepository
+	reportDir string
+	// jobTTL is how long finished jobs and their rejected rows reports are
+	// kept; they are kept forever when it is not positive
+	jobTTL time.Duration
+
+	mu   sync.RWMutex
+	jobs map[string]*domain.ImportJob
+}
+
+func NewImportUseCase(pensionImporter *importer.Importer, batchRepo domain.ImportBatchRepository, reportDir string, jobTTL time.Duration) domain.ImportUseCase {
+	return &importUseCase{
+		importer:  pensionImporter,
+		batchRepo: batchRepo,
+		reportDir: reportDir,
+		jobTTL:    jobTTL,
+		jobs:      make(map[string]*domain.ImportJob),
+	}
+}
+
+// ValidateImport runs a dry run of the import over the uploaded file
+func (u *importUseCase) ValidateImport(fileName string, src io.Reader, opts domain.ImportOptions) (*domain.ValidationReport, error) {
+	filePath, err := saveUpload(fileName, src)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(filePath)
+
+	report, err := u.importer.Validate(filePath, opts)
+	if err != nil {
+		return nil, err
+	}
+	report.FileName = fileName
+	return report, nil
+}
+
+// StartImport stores the uploaded workbook in a temporary file and processes
+// it in the background. The returned job can be polled with GetImportJob.
+func (u *importUseCase) StartImport(fileName string, src io.Reader, opts domain.ImportOptions) (*domain.ImportJob, error) {
+	u.prune()
+
+	filePath, err := saveUpload(fileName, src)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+
+	job := &domain.ImportJob{
+		ID:        id,
+		FileName:  fileName,
+		Status:    domain.ImportStatusPending,
+		CreatedAt: time.Now(),
+		Options:   opts,
+	}
+
+	u.mu.Lock()
+	u.jobs[id] = job
+	snapshot := *job
+	u.mu.Unlock()
+
+	opts.FileName = fileName
+	go u.run(id, filePath, opts)
+
+	return &snapshot, nil
+}
+
+func (u *importUseCase) GetImportJob(id string) (*domain.ImportJob, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	job, ok := u.jobs[id]
+	if !ok {
+		return nil, errors.New("import job not found")
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// GetRejectReport returns the job if it produced a rejected rows workbook
+func (u *importUseCase) GetRejectReport(id string) (*domain.ImportJob, error) {
+	job, err := u.GetImportJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if !job.HasRejectReport {
+		return nil, errors.New("import job has no rejected rows report")
+	}
+	return job, nil
+}
+
+func (u *importUseCase) GetImportBatches() ([]domain.ImportBatch, error) {
+	return u.batchRepo.FindAll()
+}
+
+func (u *importUseCase) GetImportBatch(id uint) (*domain.ImportBatch, error) {
+	return u.batchRepo.FindByID(id)
+}
+
+// RollbackImportBatch deletes every pension record created by a finished
+// import batch. Records it only updated keep their new values.
+func (u *importUseCase) RollbackImportBatch(id uint) (*domain.ImportBatch, error) {
+	batch, err := u.batchRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch batch.Status {
+	case domain.ImportStatusRunning:
+		return nil, errors.New("import batch is still running")
+	case domain.ImportStatusRolledBack:
+		return nil, errors.New("import batch is already rolled back")
+	}
+
+	if err := u.batchRepo.Rollback(batch); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+func (u *importUseCase) run(id, filePath string, opts domain.ImportOptions) {
+	defer os.Remove(filePath)
+
+	opts.RejectReportPath = filepath.Join(u.reportDir, id+importer.RejectReportSuffix)
+
+	u.update(id, func(job *domain.ImportJob) {
+		now := time.Now()
+		job.Status = domain.ImportStatusRunning
+		job.StartedAt = &now
+	})
+
+	summary, err := u.importer.ImportFile(filePath, opts, func(s domain.ImportSummary) {
+		u.update(id, func(job *domain.ImportJob) {
+			job.ImportSummary = s
+		})
+	})
+
+	u.update(id, func(job *domain.ImportJob) {
+		now := time.Now()
+		job.FinishedAt = &now
+		job.ImportSummary = summary
+		if _, statErr := os.Stat(opts.RejectReportPath); statErr == nil {
+			job.RejectReport = opts.RejectReportPath
+			job.HasRejectReport = true
+		}
+		if err != nil {
+			log.Printf("Import job %s failed: %v", id, err)
+			job.Status = domain.ImportStatusFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = domain.ImportStatusCompleted
+	})
+}
+
+// prune forgets the jobs finished more than jobTTL ago and deletes the
+// rejected rows reports older than that, including those of earlier runs of
+// the server
+func (u *importUseCase) prune() {
+	if u.jobTTL <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-u.jobTTL)
+
+	u.mu.Lock()
+	for id, job := range u.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(u.jobs, id)
+		}
+	}
+	u.mu.Unlock()
+
+	if _, err := importer.PruneRejectReports(u.reportDir, cutoff); err != nil {
+		log.Printf("error deleting expired rejected rows reports: %v", err)
+	}
+}
+
+func (u *importUseCase) update(id string, fn func(job *domain.ImportJob)) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if job, ok := u.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// saveUpload copies an uploaded file to a temporary file that keeps its
+// extension, so that the importer can detect the format
+func saveUpload(fileName string, src io.Reader) (string, error) {
+	tmp, err := os.CreateTemp("", "cnr-import-*"+filepath.Ext(fileName))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
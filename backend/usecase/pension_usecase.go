@@ -14,12 +14,20 @@ func (u *pensionUseCase) CreatePension(pension *domain.PensionData) error {
 	return u.pensionRepo.Create(pension)
 }
 
+func (u *pensionUseCase) CreatePensions(pensions []domain.PensionData, batchSize int) error {
+	return u.pensionRepo.CreateBatch(pensions, batchSize)
+}
+
+func (u *pensionUseCase) UpsertPensions(pensions []domain.PensionData, key domain.UpsertKey, batchSize int) (domain.UpsertResult, error) {
+	return u.pensionRepo.UpsertBatch(pensions, key, batchSize)
+}
+
 func (u *pensionUseCase) GetPension(id uint) (*domain.PensionData, error) {
 	return u.pensionRepo.FindByID(id)
 }
 
-func (u *pensionUseCase) GetAllPensions(avantages []string) ([]domain.PensionData, int64, error) {
-	return u.pensionRepo.FindAll(avantages)
+func (u *pensionUseCase) GetAllPensions(query domain.PensionQuery) ([]domain.PensionData, int64, error) {
+	return u.pensionRepo.FindAll(query)
 }
 
 func (u *pensionUseCase) UpdatePension(pension *domain.PensionData) error {
@@ -30,6 +38,31 @@ func (u *pensionUseCase) DeletePension(id uint) error {
 	return u.pensionRepo.Delete(id)
 }
 
-func (u *pensionUseCase) GetRiskLevelStats(wilaya string, categories []string, avantages []string) ([]domain.RiskLevelStats, error) {
-	return u.pensionRepo.GetRiskLevelStats(wilaya, categories, avantages)
+func (u *pensionUseCase) GetRiskLevelStats(filter domain.PensionFilter) ([]domain.RiskLevelStats, error) {
+	return u.pensionRepo.GetRiskLevelStats(filter)
+}
+
+func (u *pensionUseCase) GetWilayaStats(filter domain.PensionFilter) ([]domain.WilayaStats, error) {
+	return u.pensionRepo.GetWilayaStats(filter)
+}
+
+func (u *pensionUseCase) GetDemographics(filter domain.PensionFilter) (*domain.Demographics, error) {
+	return u.pensionRepo.GetDemographics(filter)
+}
+
+func (u *pensionUseCase) Pivot(query domain.PivotQuery) (*domain.PivotResult, error) {
+	return u.pensionRepo.Pivot(query)
+}
+
+// exportBatchSize is the number of rows read at a time by exports
+const exportBatchSize = 1000
+
+func (u *pensionUseCase) ExportPensions(filter domain.PensionFilter, fn func(pension *domain.PensionData) error) error {
+	return u.pensionRepo.ForEach(filter, exportBatchSize, fn)
+}
+
+func (u *pensionUseCase) Transaction(fn func(uc domain.PensionUseCase) error) error {
+	return u.pensionRepo.Transaction(func(repo domain.PensionRepository) error {
+		return fn(&pensionUseCase{pensionRepo: repo})
+	})
 }
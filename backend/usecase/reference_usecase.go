@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"cnr-tp/domain"
+	"fmt"
+	"strings"
+)
+
+type referenceUseCase struct {
+	referenceRepo domain.ReferenceRepository
+}
+
+func NewReferenceUseCase(referenceRepo domain.ReferenceRepository) domain.ReferenceUseCase {
+	return &referenceUseCase{referenceRepo: referenceRepo}
+}
+
+func (u *referenceUseCase) GetReferenceData() (*domain.ReferenceData, error) {
+	return u.referenceRepo.Get()
+}
+
+// SaveAvantageCategory checks that the category does not take codes held by
+// another one before saving it
+func (u *referenceUseCase) SaveAvantageCategory(category *domain.AvantageCategory) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" || category.Name == domain.AvantageEmpty {
+		return fmt.Errorf("%w: invalid avantage category name %q", domain.ErrInvalidReference, category.Name)
+	}
+	if category.Label == "" {
+		category.Label = category.Name
+	}
+	for i, code := range category.Codes {
+		category.Codes[i] = strings.TrimSpace(code)
+		if category.Codes[i] == "" || category.Codes[i] == "0" {
+			return fmt.Errorf("%w: invalid AVT code %q", domain.ErrInvalidReference, code)
+		}
+	}
+
+	data, err := u.referenceRepo.Get()
+	if err != nil {
+		return err
+	}
+	for _, other := range data.AvantageCategories {
+		if other.Name == category.Name {
+			continue
+		}
+		for _, code := range category.Codes {
+			for _, c := range other.Codes {
+				if c == code {
+					return fmt.Errorf("%w: code %q already belongs to %q", domain.ErrInvalidReference, code, other.Name)
+				}
+			}
+		}
+	}
+
+	return u.referenceRepo.SaveAvantageCategory(category)
+}
+
+func (u *referenceUseCase) DeleteAvantageCategory(name string) error {
+	return u.referenceRepo.DeleteAvantageCategory(name)
+}
+
+// SavePensionCategory checks that the category does not take AgeMoyenCat
+// values held by another one before saving it
+func (u *referenceUseCase) SavePensionCategory(category *domain.PensionCategory) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return fmt.Errorf("%w: pension category name is required", domain.ErrInvalidReference)
+	}
+	if category.Label == "" {
+		category.Label = category.Name
+	}
+
+	data, err := u.referenceRepo.Get()
+	if err != nil {
+		return err
+	}
+	for _, other := range data.PensionCategories {
+		if other.Name == category.Name {
+			continue
+		}
+		for _, value := range category.AgeMoyenCats {
+			for _, v := range other.AgeMoyenCats {
+				if v == value {
+					return fmt.Errorf("%w: AgeMoyenCat %d already belongs to %q", domain.ErrInvalidReference, value, other.Name)
+				}
+			}
+		}
+	}
+
+	return u.referenceRepo.SavePensionCategory(category)
+}
+
+func (u *referenceUseCase) DeletePensionCategory(name string) error {
+	return u.referenceRepo.DeletePensionCategory(name)
+}
+
+func (u *referenceUseCase) SaveWilaya(wilaya *domain.Wilaya) error {
+	wilaya.Name = strings.TrimSpace(wilaya.Name)
+	if wilaya.Code <= 0 {
+		return fmt.Errorf("%w: invalid wilaya code %d", domain.ErrInvalidReference, wilaya.Code)
+	}
+	if wilaya.Name == "" {
+		return fmt.Errorf("%w: wilaya name is required", domain.ErrInvalidReference)
+	}
+	return u.referenceRepo.SaveWilaya(wilaya)
+}
+
+// DeleteWilaya refuses to delete a wilaya that still has agencies or
+// communes, as their records would lose their wilaya
+func (u *referenceUseCase) DeleteWilaya(code int) error {
+	data, err := u.referenceRepo.Get()
+	if err != nil {
+		return err
+	}
+	for _, agency := range data.Agencies {
+		if agency.WilayaCode == code {
+			return fmt.Errorf("%w: wilaya %d still has agency %d", domain.ErrInvalidReference, code, agency.Code)
+		}
+	}
+	for _, commune := range data.Communes {
+		if commune.WilayaCode == code {
+			return fmt.Errorf("%w: wilaya %d still has commune %d", domain.ErrInvalidReference, code, commune.Code)
+		}
+	}
+	return u.referenceRepo.DeleteWilaya(code)
+}
+
+// SaveAgency checks that the agency belongs to a known wilaya before saving it
+func (u *referenceUseCase) SaveAgency(agency *domain.Agency) error {
+	agency.Name = strings.TrimSpace(agency.Name)
+	if agency.Code <= 0 {
+		return fmt.Errorf("%w: invalid agency code %d", domain.ErrInvalidReference, agency.Code)
+	}
+	if agency.Name == "" {
+		return fmt.Errorf("%w: agency name is required", domain.ErrInvalidReference)
+	}
+	if err := u.checkWilaya(agency.WilayaCode); err != nil {
+		return err
+	}
+	return u.referenceRepo.SaveAgency(agency)
+}
+
+func (u *referenceUseCase) DeleteAgency(code int8) error {
+	return u.referenceRepo.DeleteAgency(code)
+}
+
+// SaveCommune checks that the commune belongs to a known wilaya before saving it
+func (u *referenceUseCase) SaveCommune(commune *domain.Commune) error {
+	commune.Name = strings.TrimSpace(commune.Name)
+	if commune.Code <= 0 {
+		return fmt.Errorf("%w: invalid commune code %d", domain.ErrInvalidReference, commune.Code)
+	}
+	if commune.Name == "" {
+		return fmt.Errorf("%w: commune name is required", domain.ErrInvalidReference)
+	}
+	if err := u.checkWilaya(commune.WilayaCode); err != nil {
+		return err
+	}
+	return u.referenceRepo.SaveCommune(commune)
+}
+
+func (u *referenceUseCase) DeleteCommune(code int) error {
+	return u.referenceRepo.DeleteCommune(code)
+}
+
+// checkWilaya reports ErrInvalidReference when no wilaya has the given code
+func (u *referenceUseCase) checkWilaya(code int) error {
+	data, err := u.referenceRepo.Get()
+	if err != nil {
+		return err
+	}
+	if _, ok := data.Wilaya(code); !ok {
+		return fmt.Errorf("%w: unknown wilaya code %d", domain.ErrInvalidReference, code)
+	}
+	return nil
+}
+
+// SaveRiskLevel relabels one of the predicted risk levels
+func (u *referenceUseCase) SaveRiskLevel(level *domain.RiskLevel) error {
+	level.Label = strings.TrimSpace(level.Label)
+	if level.Level < 0 || level.Level > 2 {
+		return fmt.Errorf("%w: invalid risk level %d, expected 0, 1 or 2", domain.ErrInvalidReference, level.Level)
+	}
+	if level.Label == "" {
+		return fmt.Errorf("%w: risk level label is required", domain.ErrInvalidReference)
+	}
+	return u.referenceRepo.SaveRiskLevel(level)
+}
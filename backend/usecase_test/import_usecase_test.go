@@ -0,0 +1,122 @@
+package usecase_test
+
+import (
+	"cnr-tp/domain"
+	"cnr-tp/importer"
+	"cnr-tp/usecase"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubBatchRepository serves one batch and counts the rollbacks it runs
+type stubBatchRepository struct {
+	domain.ImportBatchRepository
+	batch     domain.ImportBatch
+	deleted   int
+	rollbacks int
+}
+
+func (r *stubBatchRepository) FindByID(id uint) (*domain.ImportBatch, error) {
+	batch := r.batch
+	return &batch, nil
+}
+
+func (r *stubBatchRepository) Rollback(batch *domain.ImportBatch) error {
+	r.rollbacks++
+	batch.Status = domain.ImportStatusRolledBack
+	batch.RowsDeleted = r.deleted
+	return nil
+}
+
+func TestRollbackImportBatch(t *testing.T) {
+	repo := &stubBatchRepository{batch: domain.ImportBatch{ID: 3, Status: domain.ImportStatusCompleted}, deleted: 120}
+	uc := usecase.NewImportUseCase(nil, repo, t.TempDir(), 0)
+
+	batch, err := uc.RollbackImportBatch(3)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, repo.rollbacks)
+	assert.Equal(t, domain.ImportStatusRolledBack, batch.Status)
+	assert.Equal(t, 120, batch.RowsDeleted)
+
+	// Failed batches may have committed some chunks, so they can be rolled back too
+	repo.batch.Status = domain.ImportStatusFailed
+	_, err = uc.RollbackImportBatch(3)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, repo.rollbacks)
+}
+
+func TestRollbackImportBatch_Refused(t *testing.T) {
+	for _, status := range []domain.ImportStatus{domain.ImportStatusRunning, domain.ImportStatusRolledBack} {
+		repo := &stubBatchRepository{batch: domain.ImportBatch{ID: 3, Status: status}}
+		uc := usecase.NewImportUseCase(nil, repo, t.TempDir(), 0)
+
+		batch, err := uc.RollbackImportBatch(3)
+		assert.Error(t, err, status)
+		assert.Nil(t, batch)
+		assert.Zero(t, repo.rollbacks, status)
+	}
+}
+
+// discardPensionUseCase accepts every record without storing it
+type discardPensionUseCase struct {
+	domain.PensionUseCase
+}
+
+func (discardPensionUseCase) CreatePensions(pensions []domain.PensionData, batchSize int) error {
+	return nil
+}
+
+// waitForJob polls an import job until it is finished
+func waitForJob(t *testing.T, uc domain.ImportUseCase, id string) *domain.ImportJob {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := uc.GetImportJob(id)
+		require.NoError(t, err)
+		if job.FinishedAt != nil {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("import job %s did not finish", id)
+	return nil
+}
+
+func TestImportJob_Expires(t *testing.T) {
+	mapping, err := importer.LoadMapping("../config/column_mapping.json")
+	require.NoError(t, err, "Failed to load column mapping")
+	im := importer.New(discardPensionUseCase{}, nil, nil, mapping, domain.ImportOptions{Delimiter: ";"})
+
+	const ttl = 200 * time.Millisecond
+	uc := usecase.NewImportUseCase(im, nil, t.TempDir(), ttl)
+	content := "AG;AVT;NPens;EtatPens;DateNais;DateJouis;SexeTP;NetMens;TauxD;TauxRV;TauxGLB;AgeAppTP;DureePension;AgeMoyenCat;RisqueAge;NiveauRisquePredit\n" +
+		"16;1;P1;A;1950-03-01;2010-01-01;M;abc;80;0;80;60;14;3;1;2\n"
+
+	first, err := uc.StartImport("first.csv", strings.NewReader(content), domain.ImportOptions{})
+	require.NoError(t, err)
+	job := waitForJob(t, uc, first.ID)
+	require.True(t, job.HasRejectReport)
+
+	// Test: a job finished more than the TTL ago is forgotten with its report
+	// when the next import starts
+	time.Sleep(ttl + 50*time.Millisecond)
+	second, err := uc.StartImport("second.csv", strings.NewReader(content), domain.ImportOptions{})
+	require.NoError(t, err)
+	waitForJob(t, uc, second.ID)
+
+	_, err = uc.GetImportJob(first.ID)
+	assert.Error(t, err, "Expired job should be forgotten")
+	_, err = os.Stat(job.RejectReport)
+	assert.True(t, os.IsNotExist(err), "Expired report should be deleted")
+
+	job, err = uc.GetImportJob(second.ID)
+	assert.NoError(t, err, "Recent job should be kept")
+	_, err = os.Stat(job.RejectReport)
+	assert.NoError(t, err, "Recent report should be kept")
+}
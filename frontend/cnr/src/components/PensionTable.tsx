@@ -1,5 +1,13 @@
-import React, { useMemo } from "react";
-import type { PensionData } from "../contexts/DashboardContext";
+import React from "react";
+import type {
+  PensionData,
+  ReferenceData,
+} from "../contexts/DashboardContext";
+import {
+  avantageCategoryOf,
+  pensionCategoryOf,
+  riskLevelLabel,
+} from "../utils/reference";
 
 interface Pagination {
   page: number;
@@ -9,6 +17,7 @@ interface Pagination {
 
 interface PensionTableProps {
   pensionData: PensionData[];
+  referenceData: ReferenceData | null;
   pagination: Pagination | null;
   loading: boolean;
   error: string | null;
@@ -34,19 +43,28 @@ const formatCurrency = (amount: number | null | undefined) => {
   }).format(doubleAmount);
 };
 
+// Code followed by its label, or whichever of the two is set
+const withLabel = (code: string | number, label: string) => {
+  if (code === "" || !label) {
+    return label || `${code}`;
+  }
+  return `${code} - ${label}`;
+};
+
 const PensionTable: React.FC<PensionTableProps> = ({
   pensionData,
+  referenceData,
   pagination,
   loading,
   error,
   onPageChange,
   onLimitChange,
 }) => {
-  // Calculate pagination
-  const startIndex = (pagination.page - 1) * pagination.limit;
-  const endIndex = startIndex + pagination.limit;
-  const paginatedData = pensionData.slice(startIndex, endIndex);
-  const totalPages = Math.ceil(pensionData.length / pagination.limit);
+  // pensionData is the current page, loaded by the server
+  const totalPages = Math.max(
+    1,
+    Math.ceil((pagination?.total ?? 0) / (pagination?.limit || 1))
+  );
 
   if (error) {
     return (
@@ -146,7 +164,7 @@ const PensionTable: React.FC<PensionTableProps> = ({
               </tr>
             </thead>
             <tbody className="bg-white divide-y divide-gray-200">
-              {paginatedData.map((pension) => (
+              {pensionData.map((pension) => (
                 <tr key={pension.id} className="hover:bg-gray-50">
                   <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
                     {pension.npens}
@@ -164,19 +182,25 @@ const PensionTable: React.FC<PensionTableProps> = ({
                     {pension.ag}
                   </td>
                   <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
-                    {pension.avt}
+                    {withLabel(
+                      pension.avt,
+                      avantageCategoryOf(referenceData, pension.avt)
+                    )}
                   </td>
                   <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
                     {pension.age_app_tp}
                   </td>
                   <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
-                    {pension.age_moyen_cat}
+                    {withLabel(
+                      pension.age_moyen_cat,
+                      pensionCategoryOf(referenceData, pension.age_moyen_cat)
+                    )}
                   </td>
                   <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
                     {pension.duree_pension}
                   </td>
                   <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
-                    {pension.niveau_risque_predit}
+                    {riskLevelLabel(referenceData, pension.niveau_risque_predit)}
                   </td>
                   <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
                     {pension.risque_age}
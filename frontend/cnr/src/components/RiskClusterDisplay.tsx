@@ -1,11 +1,22 @@
 import React from "react";
-import { type RiskLevelStats } from "../contexts/DashboardContext";
+import {
+  type ReferenceData,
+  type RiskLevelStats,
+} from "../contexts/DashboardContext";
+import { riskLevelLabel } from "../utils/reference";
 
 interface RiskClusterDisplayProps {
   data: RiskLevelStats[] | null;
+  referenceData: ReferenceData | null;
 }
 
-const RiskClusterDisplay: React.FC<RiskClusterDisplayProps> = ({ data }) => {
+// Bar colours from the lowest risk level to the highest
+const riskColors = ["bg-green-500", "bg-yellow-500", "bg-red-500"];
+
+const RiskClusterDisplay: React.FC<RiskClusterDisplayProps> = ({
+  data,
+  referenceData,
+}) => {
   if (!data || data.length === 0) {
     return (
       <div className="text-center py-4 text-gray-500">
@@ -14,24 +25,24 @@ const RiskClusterDisplay: React.FC<RiskClusterDisplayProps> = ({ data }) => {
     );
   }
 
-  const riskColors: { [key: string]: string } = {
-    "Bas risque": "bg-green-500",
-    "Haut risque": "bg-red-500",
-    "Moyen risque": "bg-yellow-500",
+  // Order the stats by risk level, the labels coming from the reference data.
+  // Labels the reference data does not know come last, in grey.
+  const levels = [...(referenceData?.risk_levels ?? [])]
+    .sort((a, b) => a.level - b.level)
+    .map((l) => riskLevelLabel(referenceData, l.level));
+  const rank = (label: string) => {
+    const i = levels.indexOf(label);
+    return i === -1 ? levels.length : i;
   };
-
-  // Sort the data to ensure Moyen risque is second
-  const sortedData = [...data].sort((a, b) => {
-    const order = {
-      "Bas risque": 1,
-      "Moyen risque": 2,
-      "Haut risque": 3,
-    };
-    return (
-      order[a.riskLevel as keyof typeof order] -
-      order[b.riskLevel as keyof typeof order]
-    );
-  });
+  const colorOf = (label: string) => {
+    const i = levels.indexOf(label);
+    return i === -1
+      ? "bg-gray-400"
+      : riskColors[Math.min(i, riskColors.length - 1)];
+  };
+  const sortedData = [...data].sort(
+    (a, b) => rank(a.riskLevel) - rank(b.riskLevel)
+  );
 
   return (
     <div className="bg-white shadow rounded-lg p-6 mb-6">
@@ -46,9 +57,9 @@ const RiskClusterDisplay: React.FC<RiskClusterDisplayProps> = ({ data }) => {
             </div>
             <div className="flex-grow bg-gray-200 rounded-full h-8 flex items-center justify-end overflow-hidden">
               <div
-                className={`${
-                  riskColors[item.riskLevel]
-                } h-full rounded-full flex items-center justify-center text-white font-bold text-sm pr-2`}
+                className={`${colorOf(
+                  item.riskLevel
+                )} h-full rounded-full flex items-center justify-center text-white font-bold text-sm pr-2`}
                 style={{ width: `${item.percentage}%` }}
               >
                 {item.percentage.toFixed(0)}%
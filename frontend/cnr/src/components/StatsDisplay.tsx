@@ -1,85 +1,32 @@
 import React from "react";
-import type { PensionData } from "../contexts/DashboardContext";
+import type { Demographics, PensionCounts } from "../contexts/DashboardContext";
 
 interface StatsDisplayProps {
-  pensionData: PensionData[] | null;
+  pensionCounts: PensionCounts | null;
+  demographics: Demographics | null;
   selectedWilaya: { name: string; code: number } | null;
-  selectedCategories: string[];
   selectedAvantages: string[];
-  typeTPCaseCount: number;
 }
 
-const getAvantageLabel = (avtCode: string | number): string => {
-  const code = typeof avtCode === "number" ? avtCode.toString() : avtCode;
-
-  if (["1", "7", "W", "Z", "4", "9", "G", "5"].includes(code)) {
-    return "direct";
-  }
-  if (["3", "2", "F", "E", "8", "J"].includes(code)) {
-    return "Veuves";
-  }
-  if (["H", "D", "Y"].includes(code)) {
-    return "fille majeur";
-  }
-  if (code === "0") {
-    return "(Vide)";
-  }
-  return "";
-};
-
-// const getRiskLevelLabel = (riskLevel: number): string => {
-//   switch (riskLevel) {
-//     case 0:
-//       return "Bas risque";
-//     case 1:
-//       return "Moyen risque";
-//     case 2:
-//       return "Haut risque";
-//     default:
-//       return "Inconnu";
-//   }
-// };
-
+// The tiles only show counts computed by the server for the current filters
 const StatsDisplay: React.FC<StatsDisplayProps> = ({
-  pensionData,
+  pensionCounts,
+  demographics,
   selectedWilaya,
-  selectedCategories,
   selectedAvantages,
-  typeTPCaseCount,
 }) => {
-  if (!pensionData) return null;
-
-  // Calculate statistics
-  const totalCases = pensionData.length;
-
-  // By Wilaya
-  const wilayaStats = selectedWilaya
-    ? pensionData.filter((p) => p.ag === selectedWilaya.code).length
-    : totalCases;
-
-  // By Avantage
-  const avantageStats =
-    selectedAvantages.length > 0
-      ? pensionData.filter((p) => {
-          const label = getAvantageLabel(p.avt);
-          if (selectedAvantages.includes("Sélectionner tout")) {
-            return label !== "";
-          }
-          return selectedAvantages.includes(label);
-        }).length
-      : totalCases;
+  if (!pensionCounts || !demographics) return null;
 
-  // By Risk Level
-  const riskStats =
-    selectedCategories.length > 0
-      ? pensionData.filter((p) => selectedCategories.includes(p.etatpens))
-          .length
-      : totalCases;
+  const totalCases = pensionCounts.total;
+  const wilayaStats = pensionCounts.wilaya;
+  // Records of the wilaya matching every filter
+  const avantageStats = demographics.total;
+  const typeTPCaseCount = demographics.total;
 
-  // Calculate percentages
-  const wilayaPercentage = ((wilayaStats / totalCases) * 100).toFixed(1);
-  const avantagePercentage = ((avantageStats / totalCases) * 100).toFixed(1);
-  const riskPercentage = ((riskStats / totalCases) * 100).toFixed(1);
+  const percentage = (count: number, of: number) =>
+    (of > 0 ? (count / of) * 100 : 0).toFixed(1);
+  const wilayaPercentage = percentage(wilayaStats, totalCases);
+  const avantagePercentage = percentage(avantageStats, wilayaStats);
 
   return (
     <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-4 gap-6 mb-8">
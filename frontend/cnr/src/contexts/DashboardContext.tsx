@@ -8,6 +8,7 @@ import React, {
 import { useAuth } from "./AuthContext";
 import { useNavigate } from "react-router-dom";
 import DashboardService from "../services/DashboardService";
+import ReferenceService from "../services/ReferenceService";
 
 export interface PensionData {
   id: number;
@@ -38,6 +39,16 @@ export interface PaginationMeta {
 
 export interface PaginatedResponse {
   data: PensionData[];
+  meta: PaginationMeta;
+}
+
+// Server-side pagination: sort entries are field names, prefixed with "-"
+// for a descending order
+export interface PensionListOptions {
+  page?: number;
+  limit?: number;
+  sort?: string[];
+  fields?: string[];
 }
 
 export interface RiskLevelStats {
@@ -46,11 +57,90 @@ export interface RiskLevelStats {
   percentage: number;
 }
 
+export interface DemographicGroup {
+  key: string;
+  label: string;
+  count: number;
+  percentage: number;
+}
+
+// Breakdown of the filtered pensions computed by the server
+export interface Demographics {
+  total: number;
+  sexe: DemographicGroup[];
+  age_bands: DemographicGroup[];
+  avantages: DemographicGroup[];
+}
+
+export interface AvantageCategory {
+  name: string;
+  label: string;
+  position: number;
+  codes: string[];
+}
+
+export interface PensionCategory {
+  name: string;
+  label: string;
+  position: number;
+  age_moyen_cats: number[];
+}
+
+export interface Wilaya {
+  code: number;
+  name: string;
+}
+
+export interface Agency {
+  code: number;
+  name: string;
+  wilaya_code: number;
+}
+
+export interface Commune {
+  code: number;
+  name: string;
+  wilaya_code: number;
+}
+
+export interface RiskLevel {
+  level: number;
+  label: string;
+}
+
+// Code lists owned by the backend, see GET /reference
+export interface ReferenceData {
+  avantage_categories: AvantageCategory[];
+  pension_categories: PensionCategory[];
+  wilayas: Wilaya[];
+  agencies: Agency[];
+  communes: Commune[];
+  risk_levels: RiskLevel[];
+}
+
+// Record counts behind the dashboard tiles: every record, and the records of
+// the selected wilaya (every record when none is selected)
+export interface PensionCounts {
+  total: number;
+  wilaya: number;
+}
+
+// Filter of the pension list, kept so that page changes reload the same list
+interface PensionListFilter {
+  agency?: string;
+  categories?: string[];
+  avantages?: string[];
+}
+
 interface DashboardContextType {
   pensionData: PensionData[] | null;
   loading: boolean;
   error: string | null;
-  refreshData: (wilaya?: string) => Promise<void>;
+  refreshData: (
+    wilaya?: string,
+    categories?: string[],
+    avantages?: string[]
+  ) => Promise<void>;
   selectedPension: PensionData | null;
   setSelectedPension: (pension: PensionData | null) => void;
   getPensionById: (id: string) => Promise<PensionData>;
@@ -63,6 +153,9 @@ interface DashboardContextType {
     categories?: string[],
     avantages?: string[]
   ) => Promise<void>;
+  referenceData: ReferenceData | null;
+  demographics: Demographics | null;
+  pensionCounts: PensionCounts | null;
 }
 
 const DashboardContext = createContext<DashboardContextType | undefined>(
@@ -88,46 +181,90 @@ export const DashboardProvider: React.FC<{ children: React.ReactNode }> = ({
   const [riskLevelStats, setRiskLevelStats] = useState<RiskLevelStats[] | null>(
     null
   );
+  const [referenceData, setReferenceData] = useState<ReferenceData | null>(
+    null
+  );
+  const [demographics, setDemographics] = useState<Demographics | null>(null);
+  const [pensionCounts, setPensionCounts] = useState<PensionCounts | null>(
+    null
+  );
+  const [listFilter, setListFilter] = useState<PensionListFilter>({});
 
-  const fetchData = useCallback(
-    async (wilaya?: string) => {
-      if (!token || !user?.role) return;
+  // Loads the current page only; the server filters and counts the records
+  const fetchData = useCallback(async () => {
+    if (!token || !user?.role) return;
 
-      try {
-        setLoading(true);
-        const response = await DashboardService.getPensions(
-          token,
-          user.role,
-          wilaya
-        );
-        setPensionData(response.data);
-        setPagination((prev) => ({
-          ...prev,
-          total: response.data.length,
-        }));
-        setError(null);
-      } catch (err) {
-        setError(err instanceof Error ? err.message : "An error occurred");
-      } finally {
-        setLoading(false);
-      }
-    },
-    [token, user?.role]
-  );
+    try {
+      setLoading(true);
+      const response = await DashboardService.getPensions(
+        token,
+        user.role,
+        listFilter.agency,
+        listFilter.categories,
+        listFilter.avantages,
+        { page: pagination.page, limit: pagination.limit }
+      );
+      setPensionData(response.data);
+      setPagination((prev) => ({
+        ...prev,
+        total: response.meta.total,
+      }));
+      setError(null);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "An error occurred");
+    } finally {
+      setLoading(false);
+    }
+  }, [token, user?.role, listFilter, pagination.page, pagination.limit]);
 
   const fetchRiskLevelStats = useCallback(
     async (wilaya?: string, categories?: string[], avantages?: string[]) => {
       if (!token || !user?.role) return;
 
+      // Only meta.total is read from these lists
+      const countOnly = { page: 1, limit: 1, fields: ["id"] };
+
       try {
-        const stats = await DashboardService.getRiskLevelStats(
-          token,
-          user.role,
-          wilaya,
-          categories,
-          avantages
-        );
+        const [stats, breakdown, all, inWilaya] = await Promise.all([
+          DashboardService.getRiskLevelStats(
+            token,
+            user.role,
+            wilaya,
+            categories,
+            avantages
+          ),
+          DashboardService.getDemographics(
+            token,
+            user.role,
+            wilaya,
+            categories,
+            avantages
+          ),
+          DashboardService.getPensions(
+            token,
+            user.role,
+            undefined,
+            undefined,
+            undefined,
+            countOnly
+          ),
+          wilaya
+            ? DashboardService.getPensions(
+                token,
+                user.role,
+                wilaya,
+                undefined,
+                undefined,
+                countOnly
+              )
+            : null,
+        ]);
         setRiskLevelStats(stats);
+        setDemographics(breakdown);
+        setPensionCounts({
+          total: all.meta.total,
+          wilaya: inWilaya ? inWilaya.meta.total : all.meta.total,
+        });
       } catch (err) {
         console.error("Error fetching risk level stats:", err);
         setError(err instanceof Error ? err.message : "An error occurred");
@@ -163,11 +300,16 @@ export const DashboardProvider: React.FC<{ children: React.ReactNode }> = ({
     }));
   }, []);
 
+  // Changing the filter goes back to the first page and reloads the list
   const refreshData = useCallback(
-    async (wilaya?: string) => {
-      await fetchData(wilaya);
+    async (wilaya?: string, categories?: string[], avantages?: string[]) => {
+      setListFilter({ agency: wilaya, categories, avantages });
+      setPagination((prev) => ({
+        ...prev,
+        page: 1,
+      }));
     },
-    [fetchData]
+    []
   );
 
   const refreshRiskStats = useCallback(
@@ -177,10 +319,27 @@ export const DashboardProvider: React.FC<{ children: React.ReactNode }> = ({
     [fetchRiskLevelStats]
   );
 
+  const fetchReferenceData = useCallback(async () => {
+    if (!token || !user?.role) return;
+
+    try {
+      setReferenceData(
+        await ReferenceService.getReferenceData(token, user.role)
+      );
+    } catch (err) {
+      console.error("Error fetching reference data:", err);
+      setError(err instanceof Error ? err.message : "An error occurred");
+    }
+  }, [token, user?.role]);
+
   useEffect(() => {
     fetchData();
+  }, [fetchData]);
+
+  useEffect(() => {
     fetchRiskLevelStats();
-  }, [fetchData, fetchRiskLevelStats]);
+    fetchReferenceData();
+  }, [fetchRiskLevelStats, fetchReferenceData]);
 
   return (
     <DashboardContext.Provider
@@ -197,6 +356,9 @@ export const DashboardProvider: React.FC<{ children: React.ReactNode }> = ({
         setLimit: handleSetLimit,
         riskLevelStats,
         refreshRiskStats,
+        referenceData,
+        demographics,
+        pensionCounts,
       }}
     >
       {children}
@@ -8,7 +8,7 @@ import RiskPieChart from "../components/RiskPieChart";
 import FilterSection from "../components/FilterSection";
 import PensionTable from "../components/PensionTable";
 import StatsDisplay from "../components/StatsDisplay";
-import { data } from "./wilaya";
+import { AVANTAGE_EMPTY, wilayaCodeOf } from "../utils/reference";
 
 interface WilayaInfo {
   name: string;
@@ -28,6 +28,9 @@ const DashboardPage: React.FC = () => {
     setLimit,
     riskLevelStats,
     refreshRiskStats,
+    referenceData,
+    demographics,
+    pensionCounts,
   } = useDashboard();
 
   const [selectedWilaya, setSelectedWilaya] = useState<WilayaInfo | null>(null);
@@ -41,22 +44,26 @@ const DashboardPage: React.FC = () => {
   const [selectedTypeTP, setSelectedTypeTP] = useState<string | null>(null);
 
   const handleRegionSelect = (wilaya: string) => {
-    // Find the wilaya code from the data object
-    const wilayaData = data[wilaya as keyof typeof data];
-    const wilayaCode = wilayaData?.value;
+    // Find the wilaya code from the reference data
+    const wilayaCode = wilayaCodeOf(referenceData, wilaya);
 
     if (selectedWilaya?.name === wilaya) {
       // If clicking the same wilaya, clear the selection
       setSelectedWilaya(null);
       setAgFilter("");
       setSelectedWilayaName(null); // Clear selected wilaya name as well
-      refreshData(); // Fetch all data
+      refreshData(undefined, selectedCategories, selectedAvantages); // Fetch all data
       refreshRiskStats(undefined, selectedCategories, selectedAvantages);
     } else {
       // If selecting a new wilaya
       setSelectedWilaya({ name: wilaya, code: wilayaCode || 0 });
       setSelectedWilayaName(wilaya); // Set selected wilaya name from map
-      refreshData(wilayaCode?.toString()); // Pass the wilaya code to filter data
+      // Pass the wilaya code to filter data
+      refreshData(
+        wilayaCode?.toString(),
+        selectedCategories,
+        selectedAvantages
+      );
       refreshRiskStats(
         wilayaCode?.toString(),
         selectedCategories,
@@ -73,13 +80,16 @@ const DashboardPage: React.FC = () => {
     if (name === "") {
       setSelectedWilaya(null);
       setManualWilayaCode("");
-      refreshData();
+      refreshData(undefined, selectedCategories, selectedAvantages);
       refreshRiskStats(undefined, selectedCategories, selectedAvantages);
     } else {
-      const wilayaData = data[name as keyof typeof data];
-      const wilayaCode = wilayaData?.value;
+      const wilayaCode = wilayaCodeOf(referenceData, name);
       setSelectedWilaya({ name: name, code: wilayaCode || 0 });
-      refreshData(wilayaCode?.toString());
+      refreshData(
+        wilayaCode?.toString(),
+        selectedCategories,
+        selectedAvantages
+      );
       refreshRiskStats(
         wilayaCode?.toString(),
         selectedCategories,
@@ -98,12 +108,12 @@ const DashboardPage: React.FC = () => {
     };
   }, [selectedWilaya]);
 
-  const categoryOptions = ["décès", "fin droit", "révision"];
+  const categoryOptions = (referenceData?.pension_categories ?? []).map(
+    (c) => c.name
+  );
   const avantageOptions = [
     "Sélectionner tout",
-    "direct",
-    "fille majeur",
-    "Veuves",
+    ...(referenceData?.avantage_categories ?? []).map((c) => c.name),
   ];
 
   const handleCategoryChange = (category: string) => {
@@ -111,6 +121,11 @@ const DashboardPage: React.FC = () => {
       const newSelected = prevSelected.includes(category)
         ? prevSelected.filter((c) => c !== category)
         : [...prevSelected, category];
+      refreshData(
+        selectedWilaya?.code.toString(),
+        newSelected,
+        selectedAvantages
+      );
       refreshRiskStats(
         selectedWilaya?.code.toString(),
         newSelected,
@@ -125,7 +140,7 @@ const DashboardPage: React.FC = () => {
       let newSelected: string[];
       if (avantage === "Sélectionner tout") {
         const allSelectableAvantages = avantageOptions.filter(
-          (opt) => opt !== "(Vide)"
+          (opt) => opt !== AVANTAGE_EMPTY
         );
         const currentlyAllSelected = allSelectableAvantages.every((item) =>
           prevSelected.includes(item)
@@ -140,6 +155,11 @@ const DashboardPage: React.FC = () => {
           ? prevSelected.filter((a) => a !== avantage)
           : [...prevSelected, avantage];
       }
+      refreshData(
+        selectedWilaya?.code.toString(),
+        selectedCategories,
+        newSelected
+      );
       refreshRiskStats(
         selectedWilaya?.code.toString(),
         selectedCategories,
@@ -149,139 +169,18 @@ const DashboardPage: React.FC = () => {
     });
   };
 
-  const getAvantageLabel = (avtCode: string | number): string => {
-    const code = typeof avtCode === "number" ? avtCode.toString() : avtCode;
-
-    if (["1", "7", "W", "Z", "4", "9", "G", "5"].includes(code)) {
-      return "direct";
-    }
-    if (["3", "2", "F", "E", "8", "J"].includes(code)) {
-      return "Veuves";
-    }
-    if (["H", "D", "Y"].includes(code)) {
-      return "fille majeur";
-    }
-    if (code === "0") {
-      return "(Vide)";
-    }
-    return "";
-  };
-
   const typeTPOptions = ["décès", "Âge Moyen Cat", "fin de droit"];
 
-  const getCategoryByAgeMoyenCat = (ageMoyenCat: number): string => {
-    if (ageMoyenCat == 79 || ageMoyenCat == 77) return "décès";
-    if (ageMoyenCat == 33 || ageMoyenCat == 48) return "fin droit";
-    if (
-      ageMoyenCat == 64 ||
-      ageMoyenCat == 68 ||
-      ageMoyenCat == 72 ||
-      ageMoyenCat == 74 ||
-      ageMoyenCat == 75
-    )
-      return "révision";
-    return "";
-  };
-
-  const filteredPensionData = useMemo(() => {
-    if (!pensionData) return null;
-    let filtered = pensionData;
-
-    console.log("Filtering pension data...", pensionData);
-
-    // Filtre par wilaya (si sélectionnée)
-    if (selectedWilaya) {
-      filtered = filtered.filter(
-        (pension) => pension.ag === selectedWilaya.code
-      );
-    }
-
-    // Filtre par catégorie (basé sur age_moyen_cat)
-    if (selectedCategories.length > 0) {
-      filtered = filtered.filter((pension) =>
-        selectedCategories.includes(
-          getCategoryByAgeMoyenCat(Number(pension.age_moyen_cat))
-        )
-      );
-    }
-
-    // Filtre par avantage
-    if (selectedAvantages.length > 0) {
-      filtered = filtered.filter((pension) => {
-        const label = getAvantageLabel(pension.avt);
-        if (selectedAvantages.includes("Sélectionner tout")) {
-          return label !== "";
-        }
-        if (selectedAvantages.includes("(Vide)")) {
-          return selectedAvantages.includes(label) || label === "";
-        }
-        return selectedAvantages.includes(label);
-      });
-    }
-
-    return filtered;
-  }, [pensionData, selectedWilaya, selectedCategories, selectedAvantages]);
-
-  // Calculate gender statistics based on filtered data
+  // Gender breakdown computed by the server for the current filters
   const genderStats = useMemo(() => {
-    if (!filteredPensionData) return [];
-
-    const maleCount = filteredPensionData.filter(
-      (p) => p.sexe_tp === "M"
-    ).length;
-    const femaleCount = filteredPensionData.filter(
-      (p) => p.sexe_tp === "F"
-    ).length;
+    const count = (sexe: string) =>
+      demographics?.sexe.find((g) => g.key === sexe)?.count ?? 0;
 
     return [
-      { name: "Homme", value: maleCount },
-      { name: "Femme", value: femaleCount },
+      { name: "Homme", value: count("M") },
+      { name: "Femme", value: count("F") },
     ];
-  }, [filteredPensionData]);
-
-  const clusterStats = useMemo(() => {
-    if (!filteredPensionData) return [];
-
-    // Filtrer par catégorie si sélectionnée
-    let data = filteredPensionData;
-    if (selectedCategories.length > 0) {
-      data = data.filter((pension) =>
-        selectedCategories.includes(
-          getCategoryByAgeMoyenCat(Number(pension.age_moyen_cat))
-        )
-      );
-    }
-
-    // Filtrer par avantage si sélectionné
-    if (selectedAvantages.length > 0) {
-      data = data.filter((pension) => {
-        const label = getAvantageLabel(pension.avt);
-        if (selectedAvantages.includes("Sélectionner tout")) {
-          return label !== "";
-        }
-        if (selectedAvantages.includes("(Vide)")) {
-          return selectedAvantages.includes(label) || label === "";
-        }
-        return selectedAvantages.includes(label);
-      });
-    }
-
-    const counts: Record<string, number> = {};
-    data.forEach((p) => {
-      let label = "Inconnu";
-      const riskValue = String(p.niveau_risque_predit);
-      if (riskValue === "0") label = "Bas risque";
-      else if (riskValue === "1") label = "Moyen risque";
-      else if (riskValue === "2") label = "Haut risque";
-      counts[label] = (counts[label] || 0) + 1;
-    });
-    const total = data.length;
-    return Object.entries(counts).map(([riskLevel, count]) => ({
-      riskLevel,
-      count,
-      percentage: total > 0 ? (count / total) * 100 : 0,
-    }));
-  }, [filteredPensionData, selectedCategories, selectedAvantages]);
+  }, [demographics]);
 
   const handleLogout = () => {
     logout();
@@ -312,7 +211,7 @@ const DashboardPage: React.FC = () => {
       wilayaCodeToRefresh = manualWilayaCode;
     }
 
-    refreshData(wilayaCodeToRefresh);
+    refreshData(wilayaCodeToRefresh, selectedCategories, selectedAvantages);
     refreshRiskStats(
       wilayaCodeToRefresh,
       selectedCategories,
@@ -320,11 +219,12 @@ const DashboardPage: React.FC = () => {
     );
   };
 
-  const dataKeys = Object.keys(data);
-  const wilayaNames = dataKeys.sort(); // Sort wilaya names alphabetically
+  const wilayaNames = (referenceData?.wilayas ?? [])
+    .map((w) => w.name)
+    .sort(); // Sort wilaya names alphabetically
 
-  const typeTPCaseCount = filteredPensionData ? filteredPensionData.length : 0;
-  const totalCaseCount = pensionData ? pensionData.length : 0;
+  const typeTPCaseCount = demographics?.total ?? 0;
+  const totalCaseCount = pensionCounts?.total ?? 0;
   const typeTPCasePercent =
     totalCaseCount > 0 ? (typeTPCaseCount / totalCaseCount) * 100 : 0;
 
@@ -422,12 +322,10 @@ const DashboardPage: React.FC = () => {
                     <p>Wilaya sélectionnée: {selectedWilaya.name}</p>
                     <p>Code: {selectedWilaya.code}</p>
                     <div className="mt-2 text-xs text-gray-500">
-                      {filteredPensionData && (
-                        <p>
-                          Nombre de pensions trouvées:{" "}
-                          {filteredPensionData.length}
-                        </p>
-                      )}
+                      <p>
+                        Nombre de pensions trouvées:{" "}
+                        {pagination.total.toLocaleString()}
+                      </p>
                     </div>
                   </div>
                 )}
@@ -494,14 +392,16 @@ const DashboardPage: React.FC = () => {
                   </select>
                 </div>
 
-                <RiskClusterDisplay data={clusterStats} />
+                <RiskClusterDisplay
+                  data={riskLevelStats}
+                  referenceData={referenceData}
+                />
 
                 <StatsDisplay
-                  pensionData={pensionData}
+                  pensionCounts={pensionCounts}
+                  demographics={demographics}
                   selectedWilaya={selectedWilaya}
-                  selectedCategories={selectedCategories}
                   selectedAvantages={selectedAvantages}
-                  typeTPCaseCount={typeTPCaseCount}
                 />
 
                 <div className="flex justify-between items-center mb-6">
@@ -543,7 +443,8 @@ const DashboardPage: React.FC = () => {
                 </div>
 
                 <PensionTable
-                  pensionData={filteredPensionData || []}
+                  pensionData={pensionData || []}
+                  referenceData={referenceData}
                   pagination={pagination}
                   loading={loading}
                   error={error}
@@ -1,7 +1,9 @@
 import type {
   PensionData,
   PaginatedResponse,
+  PensionListOptions,
   RiskLevelStats,
+  Demographics,
 } from "../contexts/DashboardContext";
 
 const API_BASE_URL = "http://localhost:8080/api/v1";
@@ -10,8 +12,10 @@ const DashboardService = {
   getPensions: async (
     token: string,
     role: string,
-    wilaya?: string,
-    avantages?: string[]
+    agency?: string,
+    categories?: string[],
+    avantages?: string[],
+    options: PensionListOptions = {}
   ): Promise<PaginatedResponse> => {
     if (!token) {
       throw new Error("No authentication token provided");
@@ -22,12 +26,27 @@ const DashboardService = {
 
     try {
       const queryParams = new URLSearchParams();
-      if (wilaya) {
-        queryParams.append("ag", wilaya);
+      // The dashboard map selects agencies by their AG code
+      if (agency) {
+        queryParams.append("agency", agency);
+      }
+      if (categories && categories.length > 0) {
+        queryParams.append("categories", categories.join(","));
       }
       if (avantages && avantages.length > 0) {
         queryParams.append("avantages", avantages.join(","));
       }
+      // Without a limit the server returns every matching record
+      if (options.limit) {
+        queryParams.append("page", String(options.page ?? 1));
+        queryParams.append("limit", String(options.limit));
+      }
+      if (options.sort && options.sort.length > 0) {
+        queryParams.append("sort", options.sort.join(","));
+      }
+      if (options.fields && options.fields.length > 0) {
+        queryParams.append("fields", options.fields.join(","));
+      }
 
       // For admin users, use /admin/pensions
       // For regular users, use /pensions
@@ -56,19 +75,9 @@ const DashboardService = {
         throw new Error("Invalid response format: expected data array");
       }
 
-      // Calculate pagination metadata on the frontend
-      // const total = data.data.length;
-      // const startIndex = (page - 1) * limit;
-      // const endIndex = startIndex + limit;
-      // const paginatedData = data.data.slice(startIndex, endIndex);
-
       return {
         data: data.data,
-        // meta: {
-        //   total,
-        //   // page,
-        //   // limit,
-        // },
+        meta: data.meta,
       };
     } catch (error) {
       console.error("Error fetching pension data:", error);
@@ -119,7 +128,7 @@ const DashboardService = {
   getRiskLevelStats: async (
     token: string,
     role: string,
-    wilaya?: string,
+    agency?: string,
     categories?: string[],
     avantages?: string[]
   ): Promise<RiskLevelStats[]> => {
@@ -132,7 +141,7 @@ const DashboardService = {
 
     try {
       const filters = {
-        wilaya: wilaya || "",
+        agency: agency || "",
         categories: categories || [],
         avantages: avantages || [],
       };
@@ -171,6 +180,55 @@ const DashboardService = {
       throw error;
     }
   },
+
+  getDemographics: async (
+    token: string,
+    role: string,
+    agency?: string,
+    categories?: string[],
+    avantages?: string[]
+  ): Promise<Demographics> => {
+    if (!token) {
+      throw new Error("No authentication token provided");
+    }
+    if (!role || (role !== "admin" && role !== "user")) {
+      throw new Error("Invalid or missing user role");
+    }
+
+    try {
+      const filters = {
+        agency: agency || "",
+        categories: categories || [],
+        avantages: avantages || [],
+      };
+
+      const endpoint =
+        role === "admin"
+          ? "admin/pensions/demographics"
+          : "user/pensions/demographics";
+      const response = await fetch(`${API_BASE_URL}/${endpoint}`, {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: `Bearer ${token.trim()}`,
+        },
+        body: JSON.stringify(filters),
+      });
+
+      if (!response.ok) {
+        const errorData = await response.json();
+        if (response.status === 401) {
+          throw new Error("Invalid or expired token");
+        }
+        throw new Error(errorData.error || "Failed to fetch demographics");
+      }
+
+      return (await response.json()) as Demographics;
+    } catch (error) {
+      console.error("Error fetching demographics:", error);
+      throw error;
+    }
+  },
 };
 
 export default DashboardService;
@@ -0,0 +1,43 @@
+import type { ReferenceData } from "../contexts/DashboardContext";
+
+const API_BASE_URL = "http://localhost:8080/api/v1";
+
+const ReferenceService = {
+  getReferenceData: async (
+    token: string,
+    role: string
+  ): Promise<ReferenceData> => {
+    if (!token) {
+      throw new Error("No authentication token provided");
+    }
+    if (!role || (role !== "admin" && role !== "user")) {
+      throw new Error("Invalid or missing user role");
+    }
+
+    try {
+      const endpoint = role === "admin" ? "admin/reference" : "user/reference";
+      const response = await fetch(`${API_BASE_URL}/${endpoint}`, {
+        method: "GET",
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: `Bearer ${token.trim()}`,
+        },
+      });
+
+      if (!response.ok) {
+        const errorData = await response.json();
+        if (response.status === 401) {
+          throw new Error("Invalid or expired token");
+        }
+        throw new Error(errorData.error || "Failed to fetch reference data");
+      }
+
+      return (await response.json()) as ReferenceData;
+    } catch (error) {
+      console.error("Error fetching reference data:", error);
+      throw error;
+    }
+  },
+};
+
+export default ReferenceService;
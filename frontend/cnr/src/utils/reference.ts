@@ -0,0 +1,45 @@
+import type { ReferenceData } from "../contexts/DashboardContext";
+
+export const AVANTAGE_EMPTY = "(Vide)";
+
+// Name of the avantage category holding an AVT code, "(Vide)" for records
+// without a code and "" for unknown codes
+export const avantageCategoryOf = (
+  reference: ReferenceData | null,
+  avtCode: string | number
+): string => {
+  const code = typeof avtCode === "number" ? avtCode.toString() : avtCode;
+  if (code === "0" || code === "") {
+    return AVANTAGE_EMPTY;
+  }
+  const category = reference?.avantage_categories.find((c) =>
+    c.codes.includes(code)
+  );
+  return category ? category.name : "";
+};
+
+// Name of the pension category holding an AgeMoyenCat value, "" when none does
+export const pensionCategoryOf = (
+  reference: ReferenceData | null,
+  ageMoyenCat: number
+): string => {
+  const category = reference?.pension_categories.find((c) =>
+    c.age_moyen_cats.includes(ageMoyenCat)
+  );
+  return category ? category.name : "";
+};
+
+export const riskLevelLabel = (
+  reference: ReferenceData | null,
+  level: number
+): string => {
+  const riskLevel = reference?.risk_levels.find((l) => l.level === level);
+  return riskLevel ? riskLevel.label : "Inconnu";
+};
+
+// Code of the wilaya with the given name, undefined when none has it
+export const wilayaCodeOf = (
+  reference: ReferenceData | null,
+  name: string
+): number | undefined =>
+  reference?.wilayas.find((w) => w.name === name)?.code;
@@ -34,6 +34,9 @@ export const useDashboardViewModel = () => {
     limit: number;
     total: number;
   } | null>(null);
+  // Page requested from the server, which returns every record without a limit
+  const [page, setPage] = useState(1);
+  const [limit, setLimit] = useState(10);
 
   const fetchPensionData = useCallback(async () => {
     if (!token) {
@@ -45,15 +48,19 @@ export const useDashboardViewModel = () => {
       setLoading(true);
       const response = await DashboardService.getPensions(
         token,
-        user?.role ?? "user"
+        user?.role ?? "user",
+        undefined,
+        undefined,
+        undefined,
+        { page, limit }
       );
       if ("data" in response && "meta" in response) {
         setPensionData(response.data as unknown as PensionData[]);
-        // setPagination({
-        //   page: response.meta.page,
-        //   limit: response.meta.limit,
-        //   total: response.meta.total,
-        // });
+        setPagination({
+          page: response.meta.page,
+          limit: response.meta.limit,
+          total: response.meta.total,
+        });
       } else {
         throw new Error("Invalid response format from server");
       }
@@ -74,7 +81,7 @@ export const useDashboardViewModel = () => {
     } finally {
       setLoading(false);
     }
-  }, [token, navigate, logout, user?.role]);
+  }, [token, navigate, logout, user?.role, page, limit]);
 
   useEffect(() => {
     fetchPensionData();
@@ -94,6 +101,8 @@ export const useDashboardViewModel = () => {
     loading,
     error,
     pagination,
+    setPage,
+    setLimit,
     handleLogout,
     refreshData,
   };